@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"rest-api-notes/internal/api/handlers"
@@ -14,6 +16,8 @@ import (
 	"rest-api-notes/internal/infrastructure/auth"
 	"rest-api-notes/internal/infrastructure/cache"
 	"rest-api-notes/internal/infrastructure/database"
+	"rest-api-notes/internal/infrastructure/notification"
+	"strings"
 	"syscall"
 	"time"
 
@@ -37,6 +41,14 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// RealIP используется в rate limit и счетчике неудачных входов по IP,
+	// поэтому заголовкам клиента без доверенного прокси не верим
+	ipExtractor, err := newIPExtractor(cfg.TRUSTED_PROXIES)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	e.IPExtractor = ipExtractor
+
 	// DB CONNECT
 	dbConfig := config.DatabaseConfig{
 		Host:     cfg.Database.Host,
@@ -59,25 +71,85 @@ func main() {
 		log.Fatal("Failed to connect to redis:", err)
 	}
 
-	jwtService := auth.NewJWTService(cfg.JWT)
+	jwtService, err := auth.NewJWTService(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
 	passwordService := auth.NewPasswordService()
-	sessionService := services.NewSessionService(client, time.Duration(cfg.JWT.JWT_REFRESH_EXPIRATION)*time.Hour)
-	twoFactorService := services.NewTwoFactorService(sessionService, cfg)
+	sessionService := services.NewSessionService(client,
+		time.Duration(cfg.JWT.JWT_REFRESH_EXPIRATION)*time.Hour,
+		time.Duration(cfg.JWT.JWT_ACCESS_EXPIRATION)*time.Hour,
+		cfg.TWO_FACTOR_MAX_ATTEMPTS, cfg.TWO_FACTOR_MAX_FAILURES,
+		time.Duration(cfg.TWO_FACTOR_LOCKOUT_MINUTES)*time.Minute)
+	totpService, err := auth.NewTOTPService(cfg.TOTP_ISSUER, cfg.TOTP_ENCRYPTION_KEY)
+	if err != nil {
+		log.Fatalf("Failed to configure TOTP: %v", err)
+	}
+	codeSenders, err := notification.NewCodeSenders(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure 2FA code provider: %v", err)
+	}
+	mailer, err := notification.NewMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure mail provider: %v", err)
+	}
 
 	// REPOS
 	userRepository := repositories.NewUserRepository(db, passwordService)
+	paginator := repositories.NewPaginator(cfg.PAGINATION_CURSOR_SECRET)
+	taskRepository := repositories.NewTaskRepository(db, paginator)
+	noteRepository := repositories.NewNoteRepository(db, paginator)
+	tagRepository := repositories.NewTagRepository(db)
+	searchRepository := repositories.NewSearchRepository(db)
+	recoveryCodeRepository := repositories.NewRecoveryCodeRepository(db)
 
 	// SERVICES
-	userService := services.NewUserService(userRepository, sessionService, twoFactorService)
+	recoveryCodeService := services.NewRecoveryCodeService(recoveryCodeRepository, passwordService)
+	trustedDeviceService := services.NewTrustedDeviceService(client, cfg.TRUSTED_DEVICE_SECRET,
+		time.Duration(cfg.TRUSTED_DEVICE_TTL_DAYS)*24*time.Hour)
+	loginAttemptService := services.NewLoginAttemptService(client,
+		cfg.LOGIN_MAX_ATTEMPTS, time.Duration(cfg.LOGIN_LOCKOUT_MINUTES)*time.Minute,
+		cfg.LOGIN_IP_MAX_ATTEMPTS, time.Hour)
+	passwordResetService := services.NewPasswordResetService(client, mailer, cfg.PASSWORD_RESET_URL,
+		time.Duration(cfg.PASSWORD_RESET_TTL_MINUTES)*time.Minute)
+	emailVerificationService := services.NewEmailVerificationService(client, mailer, cfg.EMAIL_VERIFICATION_URL,
+		time.Duration(cfg.EMAIL_VERIFICATION_TTL_HOURS)*time.Hour, cfg.EMAIL_VERIFICATION_REQUIRED_FOR)
+	emailChangeService := services.NewEmailChangeService(client, mailer, cfg.EMAIL_CHANGE_CONFIRM_URL,
+		cfg.EMAIL_CHANGE_CANCEL_URL, time.Duration(cfg.EMAIL_VERIFICATION_TTL_HOURS)*time.Hour)
+	accountDeletionService := services.NewAccountDeletionService(userRepository, sessionService, client,
+		time.Duration(cfg.ACCOUNT_DELETION_GRACE_DAYS)*24*time.Hour)
+	twoFactorService := services.NewTwoFactorService(sessionService, totpService, recoveryCodeService, codeSenders)
+	userService := services.NewUserService(userRepository, sessionService, twoFactorService, recoveryCodeService,
+		trustedDeviceService, passwordService, emailVerificationService, emailChangeService, accountDeletionService)
+	if err := userService.SealLegacyTOTPSecrets(context.Background()); err != nil {
+		log.Fatalf("Failed to encrypt TOTP secrets: %v", err)
+	}
 	authService := services.NewAuthService(jwtService, passwordService,
-		userRepository, sessionService, twoFactorService)
+		userRepository, sessionService, twoFactorService, trustedDeviceService, loginAttemptService,
+		passwordResetService, emailVerificationService, emailChangeService, accountDeletionService)
+	taskService := services.NewTaskService(taskRepository)
+	noteService := services.NewNoteService(noteRepository)
+	tagService := services.NewTagService(tagRepository)
+	searchService := services.NewSearchService(searchRepository)
 
 	// HANDLERS
-	userHandler := handlers.NewUserHandler(userService, twoFactorService)
+	userHandler := handlers.NewUserHandler(userService, twoFactorService, cfg)
 	authHandler := handlers.NewAuthHandler(authService, cfg)
+	taskHandler := handlers.NewTaskHandler(taskService)
+	noteHandler := handlers.NewNoteHandler(noteService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	wellKnownHandler := handlers.NewWellKnownHandler(jwtService)
+	adminHandler := handlers.NewAdminHandler(authService)
 
 	// ROUTES
-	routes.SetupRoutes(e, cfg, jwtService, userHandler, authHandler)
+	rateLimiter := cache.NewRateLimiter(client)
+	routes.SetupRoutes(e, cfg, jwtService, sessionService, userService, rateLimiter, userHandler, authHandler, taskHandler, noteHandler, tagHandler, searchHandler, wellKnownHandler, adminHandler)
+
+	// BACKGROUND JOBS
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go accountDeletionService.Run(jobsCtx, time.Duration(cfg.ACCOUNT_PURGE_INTERVAL_MINUTES)*time.Minute)
 
 	// START
 	log.Printf("Server starting on port %s", cfg.Port)
@@ -91,6 +163,7 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -99,3 +172,33 @@ func main() {
 		e.Logger.Fatal(err)
 	}
 }
+
+// newIPExtractor - без прокси берем адрес соединения, иначе X-Forwarded-For,
+// но только от перечисленных прокси (приватные сети по умолчанию не доверенные)
+func newIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
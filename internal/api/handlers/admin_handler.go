@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type adminHandler struct {
+	authService services.AuthService
+}
+
+type AdminHandler interface {
+	UnlockUser(c echo.Context) error
+}
+
+func NewAdminHandler(authService services.AuthService) AdminHandler {
+	return &adminHandler{authService: authService}
+}
+
+func (h *adminHandler) UnlockUser(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userID, err := parseUUIDParam(c, "id", "Invalid user ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.authService.UnlockAccount(ctx, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Account unlocked successfully",
+	})
+}
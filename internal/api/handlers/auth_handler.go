@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"rest-api-notes/internal/config"
@@ -25,6 +26,11 @@ type AuthHandler interface {
 	GetNewTokens(c echo.Context) error
 	Verify2FA(c echo.Context) error
 	Resend2FA(c echo.Context) error
+	ForgotPassword(c echo.Context) error
+	ResetPassword(c echo.Context) error
+	VerifyEmail(c echo.Context) error
+	ConfirmEmailChange(c echo.Context) error
+	CancelEmailChange(c echo.Context) error
 }
 
 func NewAuthHandler(authService services.AuthService, config *config.Config) AuthHandler {
@@ -46,6 +52,10 @@ func (h *authHandler) Login(c echo.Context) error {
 	userAgent := c.Request().UserAgent()
 	userIP := c.RealIP()
 
+	if trustedCookie, err := c.Cookie(auth.CookieTrustedDevice); err == nil {
+		req.TrustedDeviceToken = trustedCookie.Value
+	}
+
 	res, session, err := h.authService.Login(ctx, req, userAgent, userIP)
 	if err != nil {
 		if err == entities.Err2FARequired {
@@ -145,6 +155,10 @@ func (h *authHandler) GetNewTokens(c echo.Context) error {
 
 	session, err := h.authService.GetNewTokens(ctx, req, userAgent, userIp)
 	if err != nil {
+		if errors.Is(err, entities.ErrRefreshTokenReused) {
+			removeCookiesFromResponse(c, h.cfg)
+			return entities.ConvertError(err)
+		}
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
@@ -166,21 +180,17 @@ func (h *authHandler) Verify2FA(c echo.Context) error {
 		return entities.NewAPIError(entities.ErrorCodeUnauthorized, "Invalid user ID")
 	}
 
-	code := c.Param("code")
-	if code == "" {
-		req := new(entities.Verify2FACodeReq)
-		if err := c.Bind(req); err != nil {
-			return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
-		}
-		if err := c.Validate(req); err != nil {
-			return err
-		}
-		code = req.Code
+	// Код приходит в пути, remember_device - в теле или query
+	req := new(entities.Verify2FACodeReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
 	}
-
-	if code == "" {
+	if req.Code == "" {
 		return entities.NewAPIError(entities.ErrorCode2FACodeInvalid, "2FA code is required")
 	}
+	if c.QueryParam("remember_device") == "true" {
+		req.RememberDevice = true
+	}
 
 	twoFactorCookie, err := c.Cookie("2fa_token")
 	if err != nil {
@@ -190,13 +200,17 @@ func (h *authHandler) Verify2FA(c echo.Context) error {
 	userAgent := c.Request().UserAgent()
 	userIP := c.RealIP()
 
-	session, err := h.authService.Verify2FACode(ctx, code, twoFactorCookie.Value, userAgent, userIP, userID)
+	session, deviceToken, err := h.authService.Verify2FACode(ctx, req.Code, twoFactorCookie.Value,
+		userAgent, userIP, userID, req.RememberDevice)
 	if err != nil {
 		return entities.ConvertError(err)
 	}
 
 	setCookiesToResponse(c, session, h.cfg)
 	removeTwoFactorCookieFromResponse(c, h.cfg)
+	if deviceToken != "" {
+		setTrustedDeviceCookieToResponse(c, h.cfg, deviceToken)
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "2FA verification successful",
@@ -344,9 +358,130 @@ func removeTwoFactorCookieFromResponse(c echo.Context, cfg *config.Config) error
 	return nil
 }
 
+func setTrustedDeviceCookieToResponse(c echo.Context, cfg *config.Config, token string) error {
+	isProduction := cfg.NODE_ENV == "production"
+	c.SetCookie(&http.Cookie{
+		Name:     auth.CookieTrustedDevice,
+		Value:    token,
+		Expires:  time.Now().Add(time.Duration(cfg.TRUSTED_DEVICE_TTL_DAYS) * 24 * time.Hour),
+		HttpOnly: true,
+		Secure:   isProduction,
+		SameSite: getSameSiteMode(isProduction),
+		Path:     cfg.JWT.JWT_PATH,
+		Domain:   cfg.JWT.JWT_DOMAIN,
+	})
+	return nil
+}
+
 func getSameSiteMode(isProduction bool) http.SameSite {
 	if isProduction {
 		return http.SameSiteLaxMode
 	}
 	return http.SameSiteNoneMode
 }
+
+func (h *authHandler) ForgotPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	req := new(entities.PasswordForgotReq)
+
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.authService.ForgotPassword(ctx, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "If an account with this email exists, a password reset link has been sent",
+	})
+}
+
+func (h *authHandler) ResetPassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	req := new(entities.PasswordResetReq)
+
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.authService.ResetPassword(ctx, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	removeCookiesFromResponse(c, h.cfg)
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Password has been reset, please login with the new password",
+	})
+}
+
+func (h *authHandler) VerifyEmail(c echo.Context) error {
+	ctx := c.Request().Context()
+	req := new(entities.EmailVerifyReq)
+
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.authService.VerifyEmail(ctx, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+func (h *authHandler) ConfirmEmailChange(c echo.Context) error {
+	ctx := c.Request().Context()
+	req := new(entities.EmailChangeTokenReq)
+
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.authService.ConfirmEmailChange(ctx, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email address changed successfully",
+	})
+}
+
+func (h *authHandler) CancelEmailChange(c echo.Context) error {
+	ctx := c.Request().Context()
+	req := new(entities.EmailChangeTokenReq)
+
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.authService.CancelEmailChange(ctx, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email address change cancelled",
+	})
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"rest-api-notes/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// getUserIDFromContext достает user_id, который кладет RequireAuth
+func getUserIDFromContext(c echo.Context) (uuid.UUID, error) {
+	userIDInterface := c.Get("user_id")
+	if userIDInterface == nil {
+		return uuid.Nil, entities.NewAPIError(entities.ErrorCodeUnauthorized, "User not authenticated")
+	}
+
+	userID, ok := userIDInterface.(uuid.UUID)
+	if !ok || userID == uuid.Nil {
+		return uuid.Nil, entities.NewAPIError(entities.ErrorCodeUnauthorized, "Invalid user ID")
+	}
+
+	return userID, nil
+}
+
+func parseUUIDParam(c echo.Context, name, message string) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		return uuid.Nil, entities.NewAPIError(entities.ErrorCodeInvalidInput, message)
+	}
+	return id, nil
+}
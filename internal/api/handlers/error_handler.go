@@ -62,23 +62,45 @@ func getHTTPStatusFromErrorCode(errorCode string) int {
 	case entities.ErrorCodeUnauthorized,
 		entities.ErrorCode2FARequired,
 		entities.ErrorCodeRefreshTokenMissing,
-		entities.ErrorCodeSessionIDMissing:
+		entities.ErrorCodeSessionIDMissing,
+		entities.ErrorCodeRefreshTokenReused:
 		return http.StatusUnauthorized
 
-	case entities.ErrorCodeForbidden:
+	case entities.ErrorCodeForbidden,
+		entities.ErrorCodeEmailNotVerified:
 		return http.StatusForbidden
 
 	case entities.ErrorCodeUserNotFound,
-		entities.ErrorCodeSessionNotFound:
+		entities.ErrorCodeSessionNotFound,
+		entities.ErrorCodeTaskNotFound,
+		entities.ErrorCodeSubTaskNotFound,
+		entities.ErrorCodeNoteNotFound,
+		entities.ErrorCodeTagNotFound,
+		entities.ErrorCodeTOTPNotEnrolled,
+		entities.ErrorCodeTrustedDeviceNotFound:
 		return http.StatusNotFound
 
 	case entities.ErrorCodeEmailTaken,
-		entities.ErrorCodeUsernameTaken:
+		entities.ErrorCodeUsernameTaken,
+		entities.ErrorCodeTagAlreadyExists,
+		entities.ErrorCodeEmailVerified,
+		entities.ErrorCodeInvalidStatusTransition:
 		return http.StatusConflict
 
+	case entities.ErrorCodeAccountLocked,
+		entities.ErrorCode2FALocked:
+		return http.StatusLocked
+
+	case entities.ErrorCode2FATooManyAttempts,
+		entities.ErrorCodeLoginThrottled:
+		return http.StatusTooManyRequests
+
 	case entities.ErrorCodeInternalError:
 		return http.StatusInternalServerError
 
+	case entities.ErrorCode2FACodeSendFailed:
+		return http.StatusBadGateway
+
 	default:
 		return http.StatusBadRequest
 	}
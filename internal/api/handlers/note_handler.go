@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type noteHandler struct {
+	noteService services.NoteService
+}
+
+type NoteHandler interface {
+	CreateNote(c echo.Context) error
+	GetNote(c echo.Context) error
+	ListNotes(c echo.Context) error
+	UpdateNote(c echo.Context) error
+	DeleteNote(c echo.Context) error
+}
+
+func NewNoteHandler(noteService services.NoteService) NoteHandler {
+	return &noteHandler{noteService: noteService}
+}
+
+func (h *noteHandler) CreateNote(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.NoteCreateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	note, err := h.noteService.CreateNote(ctx, req, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusCreated, note)
+}
+
+func (h *noteHandler) GetNote(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	noteID, err := parseUUIDParam(c, "id", "Invalid note ID")
+	if err != nil {
+		return err
+	}
+
+	note, err := h.noteService.GetNote(ctx, noteID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, note)
+}
+
+func (h *noteHandler) ListNotes(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	tagFilter, err := parseTagFilter(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return err
+	}
+
+	notes, err := h.noteService.ListNotes(ctx, userID, &entities.NoteListFilter{Tags: tagFilter}, page)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return respondWithPage(c, notes)
+}
+
+func (h *noteHandler) UpdateNote(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	noteID, err := parseUUIDParam(c, "id", "Invalid note ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.NoteUpdateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	note, err := h.noteService.UpdateNote(ctx, req, noteID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, note)
+}
+
+func (h *noteHandler) DeleteNote(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	noteID, err := parseUUIDParam(c, "id", "Invalid note ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.noteService.DeleteNote(ctx, noteID, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
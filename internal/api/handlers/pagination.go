@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// parsePageRequest читает ?cursor=&limit=&sort=. limit больше MaxPageLimit
+// не ошибка, он просто урезается при выборке
+func parsePageRequest(c echo.Context) (entities.PageRequest, error) {
+	page := entities.PageRequest{
+		Cursor: c.QueryParam("cursor"),
+		Sort:   c.QueryParam("sort"),
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return page, entities.NewAPIError(entities.ErrorCodeInvalidInput, "limit must be a positive integer")
+		}
+		page.Limit = value
+	}
+
+	return page, nil
+}
+
+// respondWithPage отдает страницу и, если есть продолжение, ставит Link: <...>; rel="next"
+func respondWithPage[T any](c echo.Context, page *entities.Page[T]) error {
+	if page.NextCursor != "" {
+		next := *c.Request().URL
+		query := next.Query()
+		query.Set("cursor", page.NextCursor)
+		next.RawQuery = query.Encode()
+
+		c.Response().Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+type searchHandler struct {
+	searchService services.SearchService
+}
+
+type SearchHandler interface {
+	Search(c echo.Context) error
+}
+
+func NewSearchHandler(searchService services.SearchService) SearchHandler {
+	return &searchHandler{searchService: searchService}
+}
+
+func (h *searchHandler) Search(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := h.searchService.Search(ctx, userID, c.QueryParam("q"), page.NormalizedLimit())
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
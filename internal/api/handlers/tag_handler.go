@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type tagHandler struct {
+	tagService services.TagService
+}
+
+type TagHandler interface {
+	CreateTag(c echo.Context) error
+	ListTags(c echo.Context) error
+	RenameTag(c echo.Context) error
+	DeleteTag(c echo.Context) error
+	MergeTag(c echo.Context) error
+	AttachTags(c echo.Context) error
+	DetachTags(c echo.Context) error
+}
+
+func NewTagHandler(tagService services.TagService) TagHandler {
+	return &tagHandler{tagService: tagService}
+}
+
+func (h *tagHandler) CreateTag(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TagCreateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	tag, err := h.tagService.CreateTag(ctx, req, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusCreated, tag)
+}
+
+func (h *tagHandler) ListTags(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := h.tagService.ListTags(ctx, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (h *tagHandler) RenameTag(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	tagID, err := parseUUIDParam(c, "id", "Invalid tag ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TagRenameReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	tag, err := h.tagService.RenameTag(ctx, req, tagID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *tagHandler) DeleteTag(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	tagID, err := parseUUIDParam(c, "id", "Invalid tag ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.tagService.DeleteTag(ctx, tagID, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *tagHandler) MergeTag(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	sourceID, err := parseUUIDParam(c, "id", "Invalid tag ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TagMergeReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	tag, err := h.tagService.MergeTags(ctx, sourceID, uuid.MustParse(req.IntoID), userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *tagHandler) AttachTags(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TagBulkReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.tagService.AttachTags(ctx, req, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Tags attached successfully",
+	})
+}
+
+func (h *tagHandler) DetachTags(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TagBulkReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.tagService.DetachTags(ctx, req, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Tags detached successfully",
+	})
+}
+
+// parseTagFilter разбирает ?tags=work,home&tag_mode=all|any (по умолчанию any)
+func parseTagFilter(c echo.Context) (entities.TagFilter, error) {
+	filter := entities.TagFilter{Mode: entities.TagMatchAny}
+
+	if mode := c.QueryParam("tag_mode"); mode != "" {
+		filter.Mode = entities.TagMatchMode(mode)
+		if !filter.Mode.IsValid() {
+			return filter, entities.NewAPIError(entities.ErrorCodeInvalidFilter, "tag_mode must be 'all' or 'any'")
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, name := range strings.Split(c.QueryParam("tags"), ",") {
+		name = services.NormalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		filter.Names = append(filter.Names, name)
+	}
+
+	return filter, nil
+}
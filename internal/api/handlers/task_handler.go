@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type taskHandler struct {
+	taskService services.TaskService
+}
+
+type TaskHandler interface {
+	CreateTask(c echo.Context) error
+	GetTask(c echo.Context) error
+	ListTasks(c echo.Context) error
+	UpdateTask(c echo.Context) error
+	DeleteTask(c echo.Context) error
+	TransitionTask(c echo.Context) error
+
+	CreateSubTask(c echo.Context) error
+	GetSubTask(c echo.Context) error
+	ListSubTasks(c echo.Context) error
+	UpdateSubTask(c echo.Context) error
+	DeleteSubTask(c echo.Context) error
+}
+
+func NewTaskHandler(taskService services.TaskService) TaskHandler {
+	return &taskHandler{taskService: taskService}
+}
+
+func (h *taskHandler) CreateTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TaskCreateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	task, err := h.taskService.CreateTask(ctx, req, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusCreated, task)
+}
+
+func (h *taskHandler) GetTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	task, err := h.taskService.GetTask(ctx, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, task)
+}
+
+func (h *taskHandler) ListTasks(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseTaskListFilter(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := h.taskService.ListTasks(ctx, userID, filter, page)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return respondWithPage(c, tasks)
+}
+
+func (h *taskHandler) UpdateTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TaskUpdateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	task, err := h.taskService.UpdateTask(ctx, req, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, task)
+}
+
+func (h *taskHandler) DeleteTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.taskService.DeleteTask(ctx, taskID, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *taskHandler) TransitionTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TaskTransitionReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	task, err := h.taskService.TransitionTask(ctx, req, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, task)
+}
+
+func (h *taskHandler) CreateSubTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.SubTaskCreateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	subTask, err := h.taskService.CreateSubTask(ctx, req, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusCreated, subTask)
+}
+
+func (h *taskHandler) GetSubTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	subTaskID, err := parseUUIDParam(c, "subtaskId", "Invalid subtask ID")
+	if err != nil {
+		return err
+	}
+
+	subTask, err := h.taskService.GetSubTask(ctx, subTaskID, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, subTask)
+}
+
+func (h *taskHandler) ListSubTasks(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	subTasks, err := h.taskService.ListSubTasks(ctx, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, subTasks)
+}
+
+func (h *taskHandler) UpdateSubTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	subTaskID, err := parseUUIDParam(c, "subtaskId", "Invalid subtask ID")
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.SubTaskUpdateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	subTask, err := h.taskService.UpdateSubTask(ctx, req, subTaskID, taskID, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, subTask)
+}
+
+func (h *taskHandler) DeleteSubTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	taskID, err := parseUUIDParam(c, "id", "Invalid task ID")
+	if err != nil {
+		return err
+	}
+
+	subTaskID, err := parseUUIDParam(c, "subtaskId", "Invalid subtask ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.taskService.DeleteSubTask(ctx, subTaskID, taskID, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parseTaskListFilter разбирает query: due_before, due_after (RFC3339),
+// overdue=true, priority=high,urgent, tags=a,b&tag_mode=all
+func parseTaskListFilter(c echo.Context) (*entities.TaskListFilter, error) {
+	filter := &entities.TaskListFilter{}
+
+	var err error
+	if filter.DueBefore, err = parseTimeQueryParam(c, "due_before"); err != nil {
+		return nil, err
+	}
+	if filter.DueAfter, err = parseTimeQueryParam(c, "due_after"); err != nil {
+		return nil, err
+	}
+	if filter.Tags, err = parseTagFilter(c); err != nil {
+		return nil, err
+	}
+
+	if overdue := c.QueryParam("overdue"); overdue != "" {
+		filter.Overdue = overdue == "true" || overdue == "1"
+	}
+
+	if priorities := c.QueryParam("priority"); priorities != "" {
+		for _, value := range strings.Split(priorities, ",") {
+			priority, err := entities.ParseTaskPriority(strings.TrimSpace(value))
+			if err != nil {
+				return nil, entities.NewAPIError(entities.ErrorCodeInvalidFilter, "Invalid priority filter")
+			}
+			filter.Priorities = append(filter.Priorities, priority)
+		}
+	}
+
+	return filter, nil
+}
+
+func parseTimeQueryParam(c echo.Context, name string) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, entities.NewAPIError(entities.ErrorCodeInvalidFilter, "Invalid "+name+", expected RFC3339 timestamp")
+	}
+	return &t, nil
+}
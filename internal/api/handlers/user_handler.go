@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"rest-api-notes/internal/config"
 	"rest-api-notes/internal/domain/entities"
 	"rest-api-notes/internal/domain/services"
+	"rest-api-notes/internal/infrastructure/auth"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -12,6 +14,7 @@ import (
 type userHandler struct {
 	userService      services.UserService
 	twoFactorService services.TwoFactorService
+	cfg              *config.Config
 }
 
 type UserHandler interface {
@@ -20,12 +23,31 @@ type UserHandler interface {
 	TwoFactorToggleRequest(c echo.Context) error
 	VerifyTwoFactorToggleRequest(c echo.Context) error
 	ResendTwoFactorCode(c echo.Context) error
+	ListSessions(c echo.Context) error
+	RevokeSession(c echo.Context) error
+	RevokeSessions(c echo.Context) error
+	EnrollTOTP(c echo.Context) error
+	ConfirmTOTP(c echo.Context) error
+	SetTwoFactorMethod(c echo.Context) error
+	CountRecoveryCodes(c echo.Context) error
+	RegenerateRecoveryCodes(c echo.Context) error
+	ListTrustedDevices(c echo.Context) error
+	RevokeTrustedDevice(c echo.Context) error
+	RevokeTrustedDevices(c echo.Context) error
+	RequestReauthCode(c echo.Context) error
+	ChangePassword(c echo.Context) error
+	ResendEmailVerification(c echo.Context) error
+	ChangeUsername(c echo.Context) error
+	RequestEmailChange(c echo.Context) error
+	DeleteAccount(c echo.Context) error
 }
 
-func NewUserHandler(userService services.UserService, twoFactorService services.TwoFactorService) UserHandler {
+func NewUserHandler(userService services.UserService, twoFactorService services.TwoFactorService,
+	config *config.Config) UserHandler {
 	return &userHandler{
 		userService:      userService,
 		twoFactorService: twoFactorService,
+		cfg:              config,
 	}
 }
 
@@ -103,10 +125,18 @@ func (h *userHandler) VerifyTwoFactorToggleRequest(c echo.Context) error {
 		return entities.NewAPIError(entities.ErrorCode2FACodeInvalid, "2FA code is required")
 	}
 
-	if err := h.userService.VerifyTwoFactorToggleRequest(ctx, userID, code); err != nil {
+	recoveryCodes, err := h.userService.VerifyTwoFactorToggleRequest(ctx, userID, code)
+	if err != nil {
 		return entities.ConvertError(err)
 	}
 
+	if recoveryCodes != nil {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":        "2FA toggle code was toggled successfully",
+			"recovery_codes": recoveryCodes,
+		})
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "2FA toggle code was toggled successfully",
 	})
@@ -133,3 +163,379 @@ func (h *userHandler) ResendTwoFactorCode(c echo.Context) error {
 		"message": "2FA toggle code resent successfully",
 	})
 }
+
+func (h *userHandler) ListSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	currentSessionID, _ := c.Get("session_id").(string)
+
+	sessions, err := h.userService.ListSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+func (h *userHandler) RevokeSession(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := parseUUIDParam(c, "id", "Invalid session ID")
+	if err != nil {
+		return entities.ConvertError(entities.ErrInvalidSessionID)
+	}
+
+	if err := h.userService.RevokeSession(ctx, userID, sessionID.String()); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeSessions - DELETE /sessions, с ?except=current текущая сессия остается
+func (h *userHandler) RevokeSessions(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	exceptSessionID := ""
+	switch c.QueryParam("except") {
+	case "":
+	case "current":
+		exceptSessionID, _ = c.Get("session_id").(string)
+	default:
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "except supports only 'current'")
+	}
+
+	if err := h.userService.RevokeAllSessions(ctx, userID, exceptSessionID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Sessions revoked successfully",
+	})
+}
+
+// EnrollTOTP - секрет, otpauth:// ссылка и QR код для приложения-аутентификатора
+func (h *userHandler) EnrollTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TOTPEnrollReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	res, err := h.userService.EnrollTOTP(ctx, userID, req)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *userHandler) ConfirmTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TOTPConfirmReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	recoveryCodes, err := h.userService.ConfirmTOTP(ctx, userID, req)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	if recoveryCodes != nil {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":        "Authenticator app 2FA enabled successfully",
+			"recovery_codes": recoveryCodes,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Authenticator app 2FA enabled successfully",
+	})
+}
+
+func (h *userHandler) SetTwoFactorMethod(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.TwoFactorMethodReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.userService.SetTwoFactorMethod(ctx, userID, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "2FA method updated successfully",
+	})
+}
+
+func (h *userHandler) CountRecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	remaining, err := h.userService.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, entities.RecoveryCodesCountRes{Remaining: remaining})
+}
+
+// RegenerateRecoveryCodes - старый набор перестает работать, новый показываем один раз
+func (h *userHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.RecoveryCodesRegenerateReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	codes, err := h.userService.RegenerateRecoveryCodes(ctx, userID, req)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, entities.RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+func (h *userHandler) ListTrustedDevices(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	currentToken := ""
+	if cookie, err := c.Cookie(auth.CookieTrustedDevice); err == nil {
+		currentToken = cookie.Value
+	}
+
+	devices, err := h.userService.ListTrustedDevices(ctx, userID, currentToken)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, devices)
+}
+
+func (h *userHandler) RevokeTrustedDevice(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	deviceID, err := parseUUIDParam(c, "id", "Invalid device ID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.userService.RevokeTrustedDevice(ctx, userID, deviceID.String()); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Trusted device revoked successfully",
+	})
+}
+
+func (h *userHandler) RevokeTrustedDevices(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.userService.RevokeAllTrustedDevices(ctx, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Trusted devices revoked successfully",
+	})
+}
+
+func (h *userHandler) RequestReauthCode(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.userService.RequestReauthCode(ctx, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "2FA code sent successfully",
+	})
+}
+
+func (h *userHandler) ChangePassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.UserChangePasswordReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	sessionID, _ := c.Get("session_id").(string)
+	if err := h.userService.ChangePassword(ctx, userID, sessionID, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+func (h *userHandler) ResendEmailVerification(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.userService.ResendEmailVerification(ctx, userID); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Verification email sent successfully",
+	})
+}
+
+func (h *userHandler) ChangeUsername(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.UserChangeUsernameReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.userService.ChangeUsername(ctx, userID, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Username changed successfully",
+	})
+}
+
+func (h *userHandler) RequestEmailChange(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.EmailChangeReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	if err := h.userService.RequestEmailChange(ctx, userID, req); err != nil {
+		return entities.ConvertError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Confirmation link sent to the new email address",
+	})
+}
+
+func (h *userHandler) DeleteAccount(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	req := new(entities.UserDeleteAccountReq)
+	if err := c.Bind(req); err != nil {
+		return entities.NewAPIError(entities.ErrorCodeInvalidInput, "Invalid request format")
+	}
+
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	deleteAt, err := h.userService.DeleteAccount(ctx, userID, req)
+	if err != nil {
+		return entities.ConvertError(err)
+	}
+
+	removeCookiesFromResponse(c, h.cfg)
+	return c.JSON(http.StatusOK, entities.UserDeleteAccountRes{
+		Message:             "Account scheduled for deletion, log in before the deletion date to cancel",
+		DeletionScheduledAt: deleteAt,
+	})
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"rest-api-notes/internal/infrastructure/auth"
+
+	"github.com/labstack/echo/v4"
+)
+
+type wellKnownHandler struct {
+	jwtService auth.JWTService
+}
+
+type WellKnownHandler interface {
+	JWKS(c echo.Context) error
+}
+
+func NewWellKnownHandler(jwtService auth.JWTService) WellKnownHandler {
+	return &wellKnownHandler{jwtService: jwtService}
+}
+
+func (h *wellKnownHandler) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.jwtService.JWKS())
+}
@@ -1,27 +1,42 @@
 package middleware
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"rest-api-notes/internal/config"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
 	"rest-api-notes/internal/infrastructure/auth"
+	"rest-api-notes/internal/infrastructure/cache"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"golang.org/x/time/rate"
 )
 
 type MiddlewareManager struct {
-	cfg         *config.Config
-	jwtService  auth.JWTService
-	rateLimiter *rate.Limiter
+	cfg            *config.Config
+	jwtService     auth.JWTService
+	sessionService services.SessionService
+	userService    services.UserService
+	rateLimiter    cache.RateLimiter
+	// rateLimitSeq нумерует лимиты в порядке регистрации маршрутов
+	rateLimitSeq atomic.Int64
 }
 
-func NewMiddlewareManager(cfg *config.Config, jwtService auth.JWTService) *MiddlewareManager {
+func NewMiddlewareManager(cfg *config.Config, jwtService auth.JWTService,
+	sessionService services.SessionService, userService services.UserService,
+	rateLimiter cache.RateLimiter) *MiddlewareManager {
 	return &MiddlewareManager{
-		cfg:         cfg,
-		jwtService:  jwtService,
-		rateLimiter: rate.NewLimiter(rate.Every(time.Minute), 100),
+		cfg:            cfg,
+		jwtService:     jwtService,
+		sessionService: sessionService,
+		userService:    userService,
+		rateLimiter:    rateLimiter,
 	}
 }
 
@@ -82,12 +97,58 @@ func (m *MiddlewareManager) StrictCORS() echo.MiddlewareFunc {
 	}
 }
 
+// RateLimit - лимит на конкретный маршрут для каждого клиента отдельно
 func (m *MiddlewareManager) RateLimit(requestsPerHour int) echo.MiddlewareFunc {
-	limiter := rate.NewLimiter(rate.Every(time.Hour/time.Duration(requestsPerHour)), requestsPerHour)
+	return m.rateLimit(requestsPerHour, false, routeScope)
+}
+
+// StrictRateLimit - RateLimit, который при недоступном Redis отклоняет запрос.
+// Для входа и проверки 2FA пропуск лимита означал бы неограниченный перебор
+func (m *MiddlewareManager) StrictRateLimit(requestsPerHour int) echo.MiddlewareFunc {
+	return m.rateLimit(requestsPerHour, true, routeScope)
+}
 
+// GlobalRateLimit - общий лимит клиента на все маршруты вместе
+func (m *MiddlewareManager) GlobalRateLimit(requestsPerHour int) echo.MiddlewareFunc {
+	return m.rateLimit(requestsPerHour, false, func(c echo.Context) string {
+		return "global"
+	})
+}
+
+func routeScope(c echo.Context) string {
+	return c.Request().Method + ":" + c.Path()
+}
+
+// У каждого лимита свой счетчик: лимит группы и лимит маршрута внутри нее видят
+// один и тот же Method:Path, и без номера в ключе запрос учитывался бы дважды.
+// Порядок регистрации маршрутов одинаковый на всех инстансах, так что номера совпадают
+func (m *MiddlewareManager) rateLimit(requestsPerHour int, failClosed bool, scope func(c echo.Context) string) echo.MiddlewareFunc {
+	id := m.rateLimitSeq.Add(1)
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			if !limiter.Allow() {
+			key := fmt.Sprintf("%d:%s:%s", id, scope(c), rateLimitClientKey(c))
+
+			res, err := m.rateLimiter.Allow(c.Request().Context(), key, requestsPerHour, time.Hour)
+			if err != nil {
+				log.Printf("Rate limiter unavailable: %v", err)
+				if failClosed {
+					return c.JSON(http.StatusServiceUnavailable, map[string]string{
+						"error":   "RATE_LIMIT_UNAVAILABLE",
+						"message": "Service temporarily unavailable. Please try again later.",
+					})
+				}
+				// Redis недоступен - лучше пропустить запрос, чем положить весь API
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(res.ResetAt.Unix(), 10))
+
+			if !res.Allowed {
+				retryAfter := int(time.Until(res.ResetAt).Seconds()) + 1
+				header.Set("Retry-After", strconv.Itoa(retryAfter))
 				return c.JSON(http.StatusTooManyRequests, map[string]string{
 					"error":   "RATE_LIMIT_EXCEEDED",
 					"message": "Too many requests. Please try again later.",
@@ -98,11 +159,19 @@ func (m *MiddlewareManager) RateLimit(requestsPerHour int) echo.MiddlewareFunc {
 	}
 }
 
+// rateLimitClientKey - авторизованного клиента (после RequireAuth/TwoFactorTokenCheck)
+// считаем по user_id, остальных по IP
+func rateLimitClientKey(c echo.Context) string {
+	if userID, ok := c.Get("user_id").(uuid.UUID); ok && userID != uuid.Nil {
+		return "user:" + userID.String()
+	}
+	return "ip:" + c.RealIP()
+}
+
 func (m *MiddlewareManager) RequireAuth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			accessToken, err := c.Cookie("accessToken")
-			log.Print(accessToken)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error":   "MISSING_ACCESS_TOKEN",
@@ -110,7 +179,7 @@ func (m *MiddlewareManager) RequireAuth() echo.MiddlewareFunc {
 				})
 			}
 
-			claims, err := m.jwtService.ValidateToken(accessToken.Value, "access")
+			claims, err := m.jwtService.ValidateToken(accessToken.Value, auth.JWTTypeAccess)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error":   "INVALID_ACCESS_TOKEN",
@@ -118,6 +187,22 @@ func (m *MiddlewareManager) RequireAuth() echo.MiddlewareFunc {
 				})
 			}
 
+			// Сессия могла быть удалена (logout, смена пароля, отзыв устройства),
+			// а подпись токена при этом еще валидна
+			revoked, err := m.sessionService.IsSessionRevoked(c.Request().Context(), claims.SessionID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error":   "INTERNAL_ERROR",
+					"message": "Failed to verify session",
+				})
+			}
+			if revoked {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error":   "SESSION_REVOKED",
+					"message": "Session has been revoked, please login again",
+				})
+			}
+
 			c.Set("user_id", claims.UserID)
 			c.Set("session_id", claims.SessionID)
 
@@ -126,6 +211,39 @@ func (m *MiddlewareManager) RequireAuth() echo.MiddlewareFunc {
 	}
 }
 
+// RequireRole пропускает только пользователей с одной из ролей. Ставится после RequireAuth,
+// роль читаем из БД, чтобы снятие прав действовало сразу, а не после истечения токена
+func (m *MiddlewareManager) RequireRole(roles ...entities.RoleType) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, ok := c.Get("user_id").(uuid.UUID)
+			if !ok || userID == uuid.Nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error":   "UNAUTHORIZED",
+					"message": "User not authenticated",
+				})
+			}
+
+			user, err := m.userService.GetUserProfile(userID)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error":   "UNAUTHORIZED",
+					"message": "User not found",
+				})
+			}
+
+			if !slices.Contains(roles, user.Role) {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error":   "FORBIDDEN",
+					"message": "Insufficient permissions",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
 func (m *MiddlewareManager) TwoFactorTokenCheck() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"rest-api-notes/internal/api/handlers"
+	"rest-api-notes/internal/api/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterAdminRoutes(g *echo.Group, adminHandler handlers.AdminHandler, m *middleware.MiddlewareManager) {
+	g.POST("/users/:id/unlock", adminHandler.UnlockUser)
+}
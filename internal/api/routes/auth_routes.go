@@ -8,10 +8,15 @@ import (
 )
 
 func RegisterAuthRoutes(g *echo.Group, authHandler handlers.AuthHandler, m *middleware.MiddlewareManager) {
-	g.POST("/login", authHandler.Login)
+	g.POST("/login", authHandler.Login, m.StrictRateLimit(10))
 	g.POST("/register", authHandler.Register)
 	g.POST("/logout", authHandler.Logout)
 	g.POST("/token/refresh", authHandler.GetNewTokens, m.RateLimit(6))
-	g.POST("/2fa/verify/:code", authHandler.Verify2FA, m.TwoFactorTokenCheck(), m.RateLimit(5))
+	g.POST("/2fa/verify/:code", authHandler.Verify2FA, m.TwoFactorTokenCheck(), m.StrictRateLimit(5))
 	g.POST("/2fa/resend", authHandler.Resend2FA, m.TwoFactorTokenCheck(), m.RateLimit(5))
+	g.POST("/password/forgot", authHandler.ForgotPassword, m.RateLimit(5))
+	g.POST("/password/reset", authHandler.ResetPassword)
+	g.POST("/email/verify", authHandler.VerifyEmail)
+	g.POST("/email/change/confirm", authHandler.ConfirmEmailChange)
+	g.POST("/email/change/cancel", authHandler.CancelEmailChange)
 }
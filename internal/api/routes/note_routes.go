@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"rest-api-notes/internal/api/handlers"
+	"rest-api-notes/internal/api/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterNoteRoutes(g *echo.Group, handlers handlers.NoteHandler, m *middleware.MiddlewareManager) {
+	g.POST("", handlers.CreateNote)
+	g.GET("", handlers.ListNotes)
+	g.GET("/:id", handlers.GetNote)
+	g.PATCH("/:id", handlers.UpdateNote)
+	g.DELETE("/:id", handlers.DeleteNote)
+}
@@ -4,18 +4,28 @@ import (
 	"rest-api-notes/internal/api/handlers"
 	"rest-api-notes/internal/api/middleware"
 	"rest-api-notes/internal/config"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/services"
 	"rest-api-notes/internal/infrastructure/auth"
+	"rest-api-notes/internal/infrastructure/cache"
 
 	"github.com/labstack/echo/v4"
 )
 
 func SetupRoutes(e *echo.Echo, cfg *config.Config, jwtService auth.JWTService,
-	userHandler handlers.UserHandler, authHandler handlers.AuthHandler) {
+	sessionService services.SessionService, userService services.UserService, rateLimiter cache.RateLimiter,
+	userHandler handlers.UserHandler, authHandler handlers.AuthHandler, taskHandler handlers.TaskHandler,
+	noteHandler handlers.NoteHandler, tagHandler handlers.TagHandler,
+	searchHandler handlers.SearchHandler, wellKnownHandler handlers.WellKnownHandler,
+	adminHandler handlers.AdminHandler) {
 	apiGroup := e.Group("/api/v1")
 
-	mM := middleware.NewMiddlewareManager(cfg, jwtService)
+	mM := middleware.NewMiddlewareManager(cfg, jwtService, sessionService, userService, rateLimiter)
 	// // Global Middleware
-	e.Use(mM.StrictCORS(), mM.RateLimit(6000))
+	e.Use(mM.StrictCORS(), mM.GlobalRateLimit(6000))
+
+	// Публичные ключи для проверки access токенов другими сервисами
+	e.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
 
 	// User group
 	userGroup := apiGroup.Group("/users")
@@ -30,4 +40,39 @@ func SetupRoutes(e *echo.Echo, cfg *config.Config, jwtService auth.JWTService,
 	authGroup.Use(mM.RateLimit(10))
 	// Auth group routes
 	RegisterAuthRoutes(authGroup, authHandler, mM)
+
+	// Task group
+	taskGroup := apiGroup.Group("/tasks")
+	// Middleware for task group
+	taskGroup.Use(mM.RequireAuth())
+	// Task group routes
+	RegisterTaskRoutes(taskGroup, taskHandler, mM)
+
+	// Note group
+	noteGroup := apiGroup.Group("/notes")
+	// Middleware for note group
+	noteGroup.Use(mM.RequireAuth())
+	// Note group routes
+	RegisterNoteRoutes(noteGroup, noteHandler, mM)
+
+	// Tag group
+	tagGroup := apiGroup.Group("/tags")
+	// Middleware for tag group
+	tagGroup.Use(mM.RequireAuth())
+	// Tag group routes
+	RegisterTagRoutes(tagGroup, tagHandler, mM)
+
+	// Search group
+	searchGroup := apiGroup.Group("/search")
+	// Middleware for search group
+	searchGroup.Use(mM.RequireAuth())
+	// Search group routes
+	RegisterSearchRoutes(searchGroup, searchHandler, mM)
+
+	// Admin group
+	adminGroup := apiGroup.Group("/admin")
+	// Middleware for admin group
+	adminGroup.Use(mM.RequireAuth(), mM.RequireRole(entities.RoleAdmin))
+	// Admin group routes
+	RegisterAdminRoutes(adminGroup, adminHandler, mM)
 }
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"rest-api-notes/internal/api/handlers"
+	"rest-api-notes/internal/api/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterSearchRoutes(g *echo.Group, handlers handlers.SearchHandler, m *middleware.MiddlewareManager) {
+	g.GET("", handlers.Search, m.RateLimit(600))
+}
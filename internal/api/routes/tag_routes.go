@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"rest-api-notes/internal/api/handlers"
+	"rest-api-notes/internal/api/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterTagRoutes(g *echo.Group, handlers handlers.TagHandler, m *middleware.MiddlewareManager) {
+	g.POST("", handlers.CreateTag)
+	g.GET("", handlers.ListTags)
+	g.PATCH("/:id", handlers.RenameTag)
+	g.DELETE("/:id", handlers.DeleteTag)
+	g.POST("/:id/merge", handlers.MergeTag)
+
+	// Bulk
+	g.POST("/attach", handlers.AttachTags)
+	g.POST("/detach", handlers.DetachTags)
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"rest-api-notes/internal/api/handlers"
+	"rest-api-notes/internal/api/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterTaskRoutes(g *echo.Group, handlers handlers.TaskHandler, m *middleware.MiddlewareManager) {
+	g.POST("", handlers.CreateTask)
+	g.GET("", handlers.ListTasks)
+	g.GET("/:id", handlers.GetTask)
+	g.PATCH("/:id", handlers.UpdateTask)
+	g.DELETE("/:id", handlers.DeleteTask)
+	g.POST("/:id/status", handlers.TransitionTask)
+
+	// SubTasks
+	g.POST("/:id/subtasks", handlers.CreateSubTask)
+	g.GET("/:id/subtasks", handlers.ListSubTasks)
+	g.GET("/:id/subtasks/:subtaskId", handlers.GetSubTask)
+	g.PATCH("/:id/subtasks/:subtaskId", handlers.UpdateSubTask)
+	g.DELETE("/:id/subtasks/:subtaskId", handlers.DeleteSubTask)
+}
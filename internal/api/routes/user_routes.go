@@ -9,10 +9,35 @@ import (
 
 func RegisterUserRoutes(g *echo.Group, handlers handlers.UserHandler, m *middleware.MiddlewareManager) {
 	g.GET("/get-profile", handlers.GetProfile)
+	g.DELETE("/me", handlers.DeleteAccount, m.RateLimit(5))
 	g.POST("/update-phone", handlers.UpdateTelephoneNumber)
 
 	//2FA
 	g.POST("/2fa/request-toggle", handlers.TwoFactorToggleRequest, m.RateLimit(1))
 	g.POST("/2fa/verify-code/:code", handlers.VerifyTwoFactorToggleRequest, m.RateLimit(3))
 	g.POST("/2fa/resend-code", handlers.ResendTwoFactorCode, m.RateLimit(3))
+	g.POST("/2fa/totp/enroll", handlers.EnrollTOTP, m.RateLimit(5))
+	g.POST("/2fa/totp/confirm", handlers.ConfirmTOTP, m.RateLimit(5))
+	g.PUT("/2fa/method", handlers.SetTwoFactorMethod, m.RateLimit(5))
+	g.GET("/2fa/recovery-codes", handlers.CountRecoveryCodes)
+	g.POST("/2fa/recovery-codes/regenerate", handlers.RegenerateRecoveryCodes, m.RateLimit(3))
+	g.POST("/2fa/request-code", handlers.RequestReauthCode, m.RateLimit(5))
+
+	// Email
+	g.POST("/email/verify/resend", handlers.ResendEmailVerification, m.RateLimit(3))
+	g.POST("/email", handlers.RequestEmailChange, m.RateLimit(5))
+	g.PUT("/username", handlers.ChangeUsername, m.RateLimit(10))
+
+	// Password
+	g.POST("/password", handlers.ChangePassword, m.RateLimit(5))
+
+	// Sessions
+	g.GET("/sessions", handlers.ListSessions)
+	g.DELETE("/sessions", handlers.RevokeSessions)
+	g.DELETE("/sessions/:id", handlers.RevokeSession)
+
+	// Trusted devices
+	g.GET("/trusted-devices", handlers.ListTrustedDevices)
+	g.DELETE("/trusted-devices", handlers.RevokeTrustedDevices)
+	g.DELETE("/trusted-devices/:id", handlers.RevokeTrustedDevice)
 }
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type DatabaseConfig struct {
@@ -21,6 +23,14 @@ type RedisConfig struct {
 	DB       int
 }
 
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
 type JWTConfig struct {
 	JWTSecret              string
 	JWT_ACCESS_EXPIRATION  int
@@ -28,6 +38,15 @@ type JWTConfig struct {
 	JWT_2FA_EXPIRATION     int
 	JWT_DOMAIN             string
 	JWT_PATH               string
+	// HS256 (по умолчанию, JWT_SECRET), RS256 или EdDSA (ключ из PEM файла)
+	JWT_SIGNING_ALG       string
+	JWT_PRIVATE_KEY_PATH  string
+	JWT_KEY_ID            string
+	JWT_VERIFICATION_KEYS string
+	JWT_ISSUER            string
+	JWT_AUDIENCE          string
+	// Допустимый рассинхрон часов в секундах при проверке exp/nbf/iat
+	JWT_CLOCK_SKEW int
 }
 
 type Config struct {
@@ -35,9 +54,47 @@ type Config struct {
 	Port              string
 	CLIENT_URL        string
 	GATEWAY_API_TOKEN string
-	JWT               JWTConfig
-	Database          DatabaseConfig
-	Redis             RedisConfig
+	TOTP_ISSUER       string
+	// Ключ шифрования TOTP секретов в БД
+	TOTP_ENCRYPTION_KEY string
+	// auto (по способу 2FA пользователя), telegram, email или log
+	TWO_FACTOR_PROVIDER string
+	TWO_FACTOR_LOG_FILE string
+	// Сколько неверных вводов выдерживает один 2FA код
+	TWO_FACTOR_MAX_ATTEMPTS int
+	// Неверных 2FA кодов пользователя (по всем кодам) до блокировки проверки и ее длительность
+	TWO_FACTOR_MAX_FAILURES    int
+	TWO_FACTOR_LOCKOUT_MINUTES int
+	TRUSTED_DEVICE_SECRET      string
+	TRUSTED_DEVICE_TTL_DAYS    int
+	// Неудачных входов в аккаунт до блокировки и ее длительность
+	LOGIN_MAX_ATTEMPTS    int
+	LOGIN_LOCKOUT_MINUTES int
+	// Неудачных входов с одного IP за час по всем аккаунтам
+	LOGIN_IP_MAX_ATTEMPTS int
+	// smtp или log, по умолчанию smtp при заданном SMTP_HOST
+	MAIL_PROVIDER string
+	MAIL_LOG_FILE string
+	// Страница фронтенда, куда ведет ссылка из письма, токен добавляется в ?token=
+	PASSWORD_RESET_URL           string
+	PASSWORD_RESET_TTL_MINUTES   int
+	EMAIL_VERIFICATION_URL       string
+	EMAIL_VERIFICATION_TTL_HOURS int
+	EMAIL_CHANGE_CONFIRM_URL     string
+	EMAIL_CHANGE_CANCEL_URL      string
+	// Сколько дней вход отменяет удаление аккаунта и как часто искать истекшие
+	ACCOUNT_DELETION_GRACE_DAYS    int
+	ACCOUNT_PURGE_INTERVAL_MINUTES int
+	// Действия через запятую, закрытые до подтверждения почты (2fa, phone), none - ничего
+	EMAIL_VERIFICATION_REQUIRED_FOR []string
+	PAGINATION_CURSOR_SECRET        string
+	// Адреса/подсети (CIDR) через запятую, от которых принимаем X-Forwarded-For.
+	// Пусто - IP клиента берется из соединения, заголовки игнорируются
+	TRUSTED_PROXIES []string
+	JWT             JWTConfig
+	Database        DatabaseConfig
+	Redis           RedisConfig
+	SMTP            SMTPConfig
 }
 
 func Load() (*Config, error) {
@@ -46,6 +103,81 @@ func Load() (*Config, error) {
 		redisDB = 0
 	}
 
+	// Курсоры пагинации подписываем отдельным секретом, если он задан.
+	// С пустым ключом подпись курсора ничего не защищает, поэтому не стартуем
+	cursorSecret := os.Getenv("PAGINATION_CURSOR_SECRET")
+	if cursorSecret == "" {
+		cursorSecret = os.Getenv("JWT_SECRET")
+	}
+	if cursorSecret == "" {
+		return nil, errors.New("PAGINATION_CURSOR_SECRET or JWT_SECRET must be set")
+	}
+
+	jwtIssuer := os.Getenv("JWT_ISSUER")
+	if jwtIssuer == "" {
+		jwtIssuer = "rest-api-notes"
+	}
+	jwtAudience := os.Getenv("JWT_AUDIENCE")
+	if jwtAudience == "" {
+		jwtAudience = jwtIssuer
+	}
+
+	// Cookie доверенного устройства позволяет пропустить 2FA, поэтому подписываем ее
+	// только своим секретом: JWT_SECRET при RS256/EdDSA может быть пустым
+	trustedDeviceSecret := os.Getenv("TRUSTED_DEVICE_SECRET")
+	if trustedDeviceSecret == "" {
+		return nil, errors.New("TRUSTED_DEVICE_SECRET must be set")
+	}
+
+	passwordResetURL := os.Getenv("PASSWORD_RESET_URL")
+	if passwordResetURL == "" {
+		passwordResetURL = strings.TrimRight(os.Getenv("CLIENT_URL"), "/") + "/reset-password"
+	}
+
+	emailVerificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if emailVerificationURL == "" {
+		emailVerificationURL = strings.TrimRight(os.Getenv("CLIENT_URL"), "/") + "/verify-email"
+	}
+
+	emailChangeConfirmURL := os.Getenv("EMAIL_CHANGE_CONFIRM_URL")
+	if emailChangeConfirmURL == "" {
+		emailChangeConfirmURL = strings.TrimRight(os.Getenv("CLIENT_URL"), "/") + "/confirm-email-change"
+	}
+	emailChangeCancelURL := os.Getenv("EMAIL_CHANGE_CANCEL_URL")
+	if emailChangeCancelURL == "" {
+		emailChangeCancelURL = strings.TrimRight(os.Getenv("CLIENT_URL"), "/") + "/cancel-email-change"
+	}
+
+	emailVerificationRequiredFor := os.Getenv("EMAIL_VERIFICATION_REQUIRED_FOR")
+	if emailVerificationRequiredFor == "" {
+		emailVerificationRequiredFor = "2fa"
+	}
+	var emailPolicyActions []string
+	for _, action := range strings.Split(emailVerificationRequiredFor, ",") {
+		action = strings.TrimSpace(action)
+		if action != "" && action != "none" {
+			emailPolicyActions = append(emailPolicyActions, action)
+		}
+	}
+
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "Rest-Api-Notes"
+	}
+
+	// Без ключа секреты приложений-аутентификаторов лежали бы в БД открытыми
+	totpEncryptionKey := os.Getenv("TOTP_ENCRYPTION_KEY")
+	if totpEncryptionKey == "" {
+		return nil, errors.New("TOTP_ENCRYPTION_KEY must be set")
+	}
+
 	return &Config{
 		Redis: RedisConfig{
 			Host:     os.Getenv("REDIS_HOST"),
@@ -61,10 +193,108 @@ func Load() (*Config, error) {
 			DBName:   os.Getenv("DB_NAME"),
 			SSLMode:  os.Getenv("DB_SSLMODE"),
 		},
-		NODE_ENV:          os.Getenv("NODE_ENV"),
-		Port:              os.Getenv("PORT"),
-		CLIENT_URL:        os.Getenv("CLIENT_URL"),
-		GATEWAY_API_TOKEN: os.Getenv("GATEWAY_API_TOKEN"),
+		NODE_ENV:              os.Getenv("NODE_ENV"),
+		Port:                  os.Getenv("PORT"),
+		CLIENT_URL:            os.Getenv("CLIENT_URL"),
+		GATEWAY_API_TOKEN:     os.Getenv("GATEWAY_API_TOKEN"),
+		TOTP_ISSUER:           totpIssuer,
+		TOTP_ENCRYPTION_KEY:   totpEncryptionKey,
+		TWO_FACTOR_PROVIDER:   os.Getenv("TWO_FACTOR_PROVIDER"),
+		TWO_FACTOR_LOG_FILE:   os.Getenv("TWO_FACTOR_LOG_FILE"),
+		TRUSTED_DEVICE_SECRET: trustedDeviceSecret,
+		TRUSTED_DEVICE_TTL_DAYS: func() int {
+			val, err := strconv.Atoi(os.Getenv("TRUSTED_DEVICE_TTL_DAYS"))
+			if err != nil || val <= 0 {
+				return 30
+			}
+			return val
+		}(),
+		TWO_FACTOR_MAX_ATTEMPTS: func() int {
+			val, err := strconv.Atoi(os.Getenv("TWO_FACTOR_MAX_ATTEMPTS"))
+			if err != nil || val <= 0 {
+				return 5
+			}
+			return val
+		}(),
+		TWO_FACTOR_MAX_FAILURES: func() int {
+			val, err := strconv.Atoi(os.Getenv("TWO_FACTOR_MAX_FAILURES"))
+			if err != nil || val <= 0 {
+				return 10
+			}
+			return val
+		}(),
+		TWO_FACTOR_LOCKOUT_MINUTES: func() int {
+			val, err := strconv.Atoi(os.Getenv("TWO_FACTOR_LOCKOUT_MINUTES"))
+			if err != nil || val <= 0 {
+				return 60
+			}
+			return val
+		}(),
+		LOGIN_MAX_ATTEMPTS: func() int {
+			val, err := strconv.Atoi(os.Getenv("LOGIN_MAX_ATTEMPTS"))
+			if err != nil || val <= 0 {
+				return 5
+			}
+			return val
+		}(),
+		LOGIN_LOCKOUT_MINUTES: func() int {
+			val, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_MINUTES"))
+			if err != nil || val <= 0 {
+				return 15
+			}
+			return val
+		}(),
+		LOGIN_IP_MAX_ATTEMPTS: func() int {
+			val, err := strconv.Atoi(os.Getenv("LOGIN_IP_MAX_ATTEMPTS"))
+			if err != nil || val <= 0 {
+				return 50
+			}
+			return val
+		}(),
+		MAIL_PROVIDER:      os.Getenv("MAIL_PROVIDER"),
+		MAIL_LOG_FILE:      os.Getenv("MAIL_LOG_FILE"),
+		PASSWORD_RESET_URL: passwordResetURL,
+		PASSWORD_RESET_TTL_MINUTES: func() int {
+			val, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_TTL_MINUTES"))
+			if err != nil || val <= 0 {
+				return 30
+			}
+			return val
+		}(),
+		EMAIL_VERIFICATION_URL: emailVerificationURL,
+		EMAIL_VERIFICATION_TTL_HOURS: func() int {
+			val, err := strconv.Atoi(os.Getenv("EMAIL_VERIFICATION_TTL_HOURS"))
+			if err != nil || val <= 0 {
+				return 24
+			}
+			return val
+		}(),
+		EMAIL_VERIFICATION_REQUIRED_FOR: emailPolicyActions,
+		EMAIL_CHANGE_CONFIRM_URL:        emailChangeConfirmURL,
+		EMAIL_CHANGE_CANCEL_URL:         emailChangeCancelURL,
+		ACCOUNT_DELETION_GRACE_DAYS: func() int {
+			val, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+			if err != nil || val <= 0 {
+				return 30
+			}
+			return val
+		}(),
+		ACCOUNT_PURGE_INTERVAL_MINUTES: func() int {
+			val, err := strconv.Atoi(os.Getenv("ACCOUNT_PURGE_INTERVAL_MINUTES"))
+			if err != nil || val <= 0 {
+				return 60
+			}
+			return val
+		}(),
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+		PAGINATION_CURSOR_SECRET: cursorSecret,
+		TRUSTED_PROXIES:          trustedProxies,
 		JWT: JWTConfig{
 			JWTSecret: os.Getenv("JWT_SECRET"),
 			JWT_ACCESS_EXPIRATION: func() int {
@@ -88,8 +318,21 @@ func Load() (*Config, error) {
 				}
 				return val
 			}(),
-			JWT_DOMAIN: os.Getenv("JWT_DOMAIN"),
-			JWT_PATH:   os.Getenv("JWT_PATH"),
+			JWT_DOMAIN:            os.Getenv("JWT_DOMAIN"),
+			JWT_PATH:              os.Getenv("JWT_PATH"),
+			JWT_SIGNING_ALG:       os.Getenv("JWT_SIGNING_ALG"),
+			JWT_PRIVATE_KEY_PATH:  os.Getenv("JWT_PRIVATE_KEY_PATH"),
+			JWT_KEY_ID:            os.Getenv("JWT_KEY_ID"),
+			JWT_VERIFICATION_KEYS: os.Getenv("JWT_VERIFICATION_KEYS"),
+			JWT_ISSUER:            jwtIssuer,
+			JWT_AUDIENCE:          jwtAudience,
+			JWT_CLOCK_SKEW: func() int {
+				val, err := strconv.Atoi(os.Getenv("JWT_CLOCK_SKEW"))
+				if err != nil {
+					return 30
+				}
+				return val
+			}(),
 		},
 	}, nil
 }
@@ -1,7 +1,10 @@
 package entities
 
 import (
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,8 +16,73 @@ var (
 	Err2FASessionAndTokenMismatch = errors.New("provided token mismatch with token from 2FA session data")
 	Err2FAAlreadyEnabled          = errors.New("2FA already enabled")
 	Err2FADisabled                = errors.New("2FA disabled")
+	ErrInvalid2FAMethod           = errors.New("invalid 2FA method")
+	ErrTOTPEnrollmentNotFound     = errors.New("TOTP enrollment not found or expired")
+	ErrTOTPNotConfigured          = errors.New("TOTP is not configured")
+	Err2FATooManyAttempts         = errors.New("too many invalid 2FA code attempts")
+	Err2FACodeRequired            = errors.New("2FA code required for this action")
+	Err2FACodeSendFailed          = errors.New("failed to send 2FA code")
+	Err2FALocked                  = errors.New("2FA verification temporarily locked")
 )
 
+// NewTwoFALockedError - проверка второго фактора заблокирована после серии неверных кодов,
+// в details время разблокировки
+func NewTwoFALockedError(until time.Time) *APIError {
+	return NewAPIError(ErrorCode2FALocked, "Too many invalid 2FA codes, verification is temporarily locked",
+		loginRetryDetails("locked_until", until))
+}
+
+// TwoFactorMethod - канал второго фактора: код в Telegram, код на почту или приложение-аутентификатор
+type TwoFactorMethod string
+
+const (
+	TwoFactorMethodTelegram TwoFactorMethod = "telegram"
+	TwoFactorMethodEmail    TwoFactorMethod = "email"
+	TwoFactorMethodTOTP     TwoFactorMethod = "totp"
+)
+
+func (m TwoFactorMethod) String() string {
+	return string(m)
+}
+
+func (m TwoFactorMethod) IsValid() bool {
+	switch m {
+	case TwoFactorMethodTelegram, TwoFactorMethodEmail, TwoFactorMethodTOTP:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m TwoFactorMethod) Value() (driver.Value, error) {
+	if !m.IsValid() {
+		return nil, fmt.Errorf("invalid 2FA method: %s", m)
+	}
+	return string(m), nil
+}
+
+func (m *TwoFactorMethod) Scan(value interface{}) error {
+	if value == nil {
+		*m = TwoFactorMethodTelegram
+		return nil
+	}
+
+	switch s := value.(type) {
+	case string:
+		*m = TwoFactorMethod(s)
+	case []byte:
+		*m = TwoFactorMethod(s)
+	default:
+		return fmt.Errorf("cannot scan %T into TwoFactorMethod", value)
+	}
+
+	if !m.IsValid() {
+		return fmt.Errorf("invalid 2FA method: %s", *m)
+	}
+
+	return nil
+}
+
 type TwoFASessionData struct {
 	Code      string              `json:"code"`
 	Context   TwoFASessionContext `json:"context"`
@@ -28,20 +96,58 @@ type TwoFASessionContext string
 const (
 	TwoFAContextLogin  TwoFASessionContext = "login"
 	TwoFAContextToggle TwoFASessionContext = "toggle"
+	// Повторное подтверждение личности перед чувствительным действием (смена пароля и т.п.)
+	TwoFAContextReauth TwoFASessionContext = "reauth"
 )
 
+type TOTPEnrollRes struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+	// PNG с QR кодом в виде data URI, можно сразу подставить в <img src>
+	QRCode string `json:"qr_code"`
+}
+
+// ReauthReq - подтверждение личности для смены настроек 2FA.
+// Нужен, только если 2FA уже включена: иначе одной украденной сессии хватило бы, чтобы ее подменить
+type ReauthReq struct {
+	CurrentPassword string `json:"current_password" validate:"omitempty,max=128"`
+	// Код 2FA из контекста reauth (или recovery код)
+	ReauthCode string `json:"reauth_code" validate:"omitempty,max=32"`
+}
+
+type TOTPEnrollReq struct {
+	ReauthReq
+}
+
+type TOTPConfirmReq struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+	ReauthReq
+}
+
+type TwoFactorMethodReq struct {
+	Method TwoFactorMethod `json:"method" validate:"required"`
+	ReauthReq
+}
+
 type SendCodeRequest struct {
 	PhoneNumber string `json:"phone_number"`
 	Code        string `json:"code"`
 }
 
 type RequestStatus struct {
-	RequestID        string  `json:"request_id"`
-	PhoneNumber      string  `json:"phone_number"`
-	RequestCost      float64 `json:"request_cost"`
-	IsRefunded       bool    `json:"is_refunded"`
-	RemainingBalance float64 `json:"remaining_balance"`
-	DeliveryStatus   DeliveryStatus
+	RequestID        string         `json:"request_id"`
+	PhoneNumber      string         `json:"phone_number"`
+	RequestCost      float64        `json:"request_cost"`
+	IsRefunded       bool           `json:"is_refunded"`
+	RemainingBalance float64        `json:"remaining_balance"`
+	DeliveryStatus   DeliveryStatus `json:"delivery_status"`
+}
+
+// TelegramGatewayResponse - ответ Telegram Gateway API, при ok=false заполнено только Error
+type TelegramGatewayResponse struct {
+	Ok     bool          `json:"ok"`
+	Result RequestStatus `json:"result"`
+	Error  string        `json:"error"`
 }
 
 type DeliveryStatus struct {
@@ -15,16 +15,20 @@ var (
 	ErrSessionIDTokenNotProvided  = errors.New("session_id token not provided")
 	Err2FARequired                = errors.New("2fa_required")
 	ErrTokensMismatch             = errors.New("mismatch refreshToken from request and refreshToken from session")
+	ErrInvalidPasswordResetToken  = errors.New("invalid or expired password reset token")
 )
 
 type JWTClaims struct {
+	ID        string
 	UserID    uuid.UUID
 	SessionID string
+	IssuedAt  time.Time
 	ExpiresAt time.Time
 	TokenType string
 }
 
 type TwoFactorJWTClaims struct {
+	ID        string
 	UserID    uuid.UUID
 	UserAgent string
 	UserIP    string
@@ -48,6 +52,8 @@ type UserAuthRes struct {
 type UserLoginReq struct {
 	Identifier string `json:"identifier" validate:"required,max=255"`
 	Password   string `json:"password" validate:"required"`
+	// Из cookie доверенного устройства, с ним 2FA при входе не спрашиваем
+	TrustedDeviceToken string `json:"-"`
 }
 
 type UserLogoutReq struct {
@@ -59,3 +65,18 @@ type UserGetNewTokensReq struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 	SessionID    string `json:"session_id" validate:"required,uuid"`
 }
+
+type PasswordForgotReq struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type PasswordResetReq struct {
+	Token    string `json:"token" validate:"required,max=128"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+// PasswordResetToken - в Redis по хэшу токена из письма, сам токен не храним
+type PasswordResetToken struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt int64     `json:"expires_at"`
+}
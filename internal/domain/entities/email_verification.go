@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailNotVerified              = errors.New("email is not verified")
+	ErrEmailAlreadyVerified          = errors.New("email already verified")
+	ErrInvalidEmailVerificationToken = errors.New("invalid or expired email verification token")
+	ErrInvalidEmailChangeToken       = errors.New("invalid or expired email change token")
+)
+
+// Действия, которые EMAIL_VERIFICATION_REQUIRED_FOR может закрыть до подтверждения почты
+const (
+	EmailPolicyAction2FA   = "2fa"
+	EmailPolicyActionPhone = "phone"
+)
+
+type EmailVerifyReq struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+// EmailVerificationToken - в Redis по хэшу токена. Email запоминаем, чтобы ссылка,
+// отправленная на старый адрес, не подтвердила уже измененный
+type EmailVerificationToken struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	ExpiresAt int64     `json:"expires_at"`
+}
+
+type EmailChangeReq struct {
+	NewEmail        string `json:"new_email" validate:"required,email,max=255"`
+	CurrentPassword string `json:"current_password" validate:"required,max=128"`
+	// Код 2FA (или recovery код), обязателен если 2FA включена
+	Code string `json:"code" validate:"omitempty,max=32"`
+}
+
+type EmailChangeTokenReq struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+// PendingEmailChange - запрошенная смена почты, ждет подтверждения с нового адреса.
+// Хэши токенов храним, чтобы ссылки из прошлого запроса не сработали
+type PendingEmailChange struct {
+	UserID      uuid.UUID `json:"user_id"`
+	OldEmail    string    `json:"old_email"`
+	NewEmail    string    `json:"new_email"`
+	ConfirmHash string    `json:"confirm_hash"`
+	CancelHash  string    `json:"cancel_hash"`
+	ExpiresAt   int64     `json:"expires_at"`
+}
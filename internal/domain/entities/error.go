@@ -8,20 +8,32 @@ const (
 	ErrorCodeSessionIDMissing    = "SESSION_ID_MISSING"
 	ErrorCode2FARequired         = "2FA_REQUIRED"
 	ErrorCodeTokensMismatch      = "TOKENS_MISMATCH"
+	ErrorCodeRefreshTokenReused  = "REFRESH_TOKEN_REUSED"
+	ErrorCodeAccountLocked       = "ACCOUNT_LOCKED"
+	ErrorCodeLoginThrottled      = "LOGIN_THROTTLED"
+	ErrorCodeResetTokenInvalid   = "PASSWORD_RESET_TOKEN_INVALID"
 
 	// 2FA errors
-	ErrorCode2FACodeExpired    = "2FA_CODE_EXPIRED"
-	ErrorCode2FACodeInvalid    = "2FA_CODE_INVALID"
-	ErrorCode2FACodeRevoked    = "2FA_CODE_REVOKED"
-	ErrorCode2FATokenMismatch  = "2FA_TOKEN_MISMATCH"
-	ErrorCode2FAAlreadyEnabled = "2FA_ALREADY_ENABLED"
-	ErrorCode2FAPhoneNotSet    = "PHONE_NOT_SET"
+	ErrorCode2FACodeExpired     = "2FA_CODE_EXPIRED"
+	ErrorCode2FACodeInvalid     = "2FA_CODE_INVALID"
+	ErrorCode2FACodeRevoked     = "2FA_CODE_REVOKED"
+	ErrorCode2FATokenMismatch   = "2FA_TOKEN_MISMATCH"
+	ErrorCode2FAAlreadyEnabled  = "2FA_ALREADY_ENABLED"
+	ErrorCode2FAPhoneNotSet     = "PHONE_NOT_SET"
+	ErrorCodeInvalid2FAMethod   = "INVALID_2FA_METHOD"
+	ErrorCodeTOTPNotEnrolled    = "TOTP_ENROLLMENT_NOT_FOUND"
+	ErrorCodeTOTPNotConfigured  = "TOTP_NOT_CONFIGURED"
+	ErrorCode2FATooManyAttempts = "2FA_TOO_MANY_ATTEMPTS"
+	ErrorCode2FACodeRequired    = "2FA_CODE_REQUIRED"
+	ErrorCode2FACodeSendFailed  = "2FA_CODE_SEND_FAILED"
+	ErrorCode2FALocked          = "2FA_LOCKED"
 
 	// Session errors
-	ErrorCodeSessionWrongDevice = "SESSION_WRONG_DEVICE"
-	ErrorCodeSessionExpired     = "SESSION_EXPIRED"
-	ErrorCodeSessionNotFound    = "SESSION_NOT_FOUND"
-	ErrorCodeInvalidSessionID   = "INVALID_SESSION_ID"
+	ErrorCodeSessionWrongDevice    = "SESSION_WRONG_DEVICE"
+	ErrorCodeSessionExpired        = "SESSION_EXPIRED"
+	ErrorCodeSessionNotFound       = "SESSION_NOT_FOUND"
+	ErrorCodeInvalidSessionID      = "INVALID_SESSION_ID"
+	ErrorCodeTrustedDeviceNotFound = "TRUSTED_DEVICE_NOT_FOUND"
 
 	// User errors
 	ErrorCodeEmailTaken         = "EMAIL_ALREADY_TAKEN"
@@ -29,6 +41,28 @@ const (
 	ErrorCodeUserNotFound       = "USER_NOT_FOUND"
 	ErrorCodeInvalidEmailFormat = "INVALID_EMAIL_FORMAT"
 	ErrorCodeCantChangePhone2FA = "CANT_CHANGE_PHONE_2FA"
+	ErrorCodeWeakPassword       = "WEAK_PASSWORD"
+	ErrorCodeCurrentPassword    = "INVALID_CURRENT_PASSWORD"
+	ErrorCodeEmailNotVerified   = "EMAIL_NOT_VERIFIED"
+	ErrorCodeEmailVerified      = "EMAIL_ALREADY_VERIFIED"
+	ErrorCodeVerifyTokenInvalid = "EMAIL_VERIFICATION_TOKEN_INVALID"
+	ErrorCodeChangeTokenInvalid = "EMAIL_CHANGE_TOKEN_INVALID"
+
+	// Task errors
+	ErrorCodeTaskNotFound            = "TASK_NOT_FOUND"
+	ErrorCodeSubTaskNotFound         = "SUBTASK_NOT_FOUND"
+	ErrorCodeInvalidTaskStatus       = "INVALID_TASK_STATUS"
+	ErrorCodeInvalidStatusTransition = "INVALID_STATUS_TRANSITION"
+	ErrorCodeInvalidTaskDates        = "INVALID_TASK_DATES"
+
+	// Note errors
+	ErrorCodeNoteNotFound = "NOTE_NOT_FOUND"
+
+	// Tag errors
+	ErrorCodeTagNotFound      = "TAG_NOT_FOUND"
+	ErrorCodeTagAlreadyExists = "TAG_ALREADY_EXISTS"
+	ErrorCodeTagMergeIntoSelf = "TAG_MERGE_INTO_SELF"
+	ErrorCodeEmptyTagName     = "EMPTY_TAG_NAME"
 
 	// General errors
 	ErrorCodeValidationFailed = "VALIDATION_FAILED"
@@ -36,6 +70,11 @@ const (
 	ErrorCodeUnauthorized     = "UNAUTHORIZED"
 	ErrorCodeForbidden        = "FORBIDDEN"
 	ErrorCodeInvalidInput     = "INVALID_INPUT"
+	ErrorCodeInvalidSortField = "INVALID_SORT_FIELD"
+	ErrorCodeInvalidFilter    = "INVALID_FILTER"
+	ErrorCodeInvalidCursor    = "INVALID_CURSOR"
+	ErrorCodeEmptySearchQuery = "EMPTY_SEARCH_QUERY"
+	ErrorCodeEmptyTitle       = "EMPTY_TITLE"
 )
 
 type APIError struct {
@@ -88,6 +127,13 @@ func (e TwoFactorRequiredError) Error() string {
 	return e.Message
 }
 
+// NewInvalid2FACodeError - неверный код, в details сколько попыток осталось до сброса кода
+func NewInvalid2FACodeError(remainingAttempts int) *APIError {
+	return NewAPIError(ErrorCode2FACodeInvalid, "Invalid 2FA code", map[string]interface{}{
+		"remaining_attempts": remainingAttempts,
+	})
+}
+
 func NewTwoFactorRequiredError() *TwoFactorRequiredError {
 	return &TwoFactorRequiredError{
 		Code:     ErrorCode2FARequired,
@@ -104,6 +150,9 @@ var ErrorMapper = map[error]*APIError{
 	ErrRefreshTokenNotProvided:    NewAPIError(ErrorCodeRefreshTokenMissing, "Refresh token not provided"),
 	ErrSessionIDTokenNotProvided:  NewAPIError(ErrorCodeSessionIDMissing, "Session ID not provided"),
 	ErrTokensMismatch:             NewAPIError(ErrorCodeTokensMismatch, "Token mismatch detected"),
+	ErrAccountLocked:              NewAPIError(ErrorCodeAccountLocked, "Account is temporarily locked due to too many failed login attempts"),
+	ErrLoginThrottled:             NewAPIError(ErrorCodeLoginThrottled, "Too many failed login attempts, try again later"),
+	ErrInvalidPasswordResetToken:  NewAPIError(ErrorCodeResetTokenInvalid, "Password reset link is invalid or has expired"),
 
 	// 2FA errors
 	Err2FACodeInvalidOrExpired:    NewAPIError(ErrorCode2FACodeExpired, "2FA code has expired"),
@@ -112,20 +161,58 @@ var ErrorMapper = map[error]*APIError{
 	Err2FASessionAndTokenMismatch: NewAPIError(ErrorCode2FATokenMismatch, "2FA token mismatch"),
 	Err2FAAlreadyEnabled:          NewAPIError(ErrorCode2FAAlreadyEnabled, "Two-factor authentication is already enabled"),
 	Err2FADisabled:                NewAPIError(ErrorCode2FAAlreadyEnabled, "Two-factor authentication is disabled"),
+	ErrInvalid2FAMethod:           NewAPIError(ErrorCodeInvalid2FAMethod, "2FA method must be one of: telegram, email, totp"),
+	ErrTOTPEnrollmentNotFound:     NewAPIError(ErrorCodeTOTPNotEnrolled, "TOTP enrollment not found or expired, start it again"),
+	ErrTOTPNotConfigured:          NewAPIError(ErrorCodeTOTPNotConfigured, "Authenticator app is not set up, enroll TOTP first"),
+	Err2FATooManyAttempts:         NewAPIError(ErrorCode2FATooManyAttempts, "Too many invalid attempts, the code was invalidated, request a new one"),
+	Err2FALocked:                  NewAPIError(ErrorCode2FALocked, "Too many invalid 2FA codes, verification is temporarily locked"),
+	Err2FACodeRequired:            NewAPIError(ErrorCode2FACodeRequired, "2FA code is required to confirm this action, request one via /users/2fa/request-code"),
+	Err2FACodeSendFailed:          NewAPIError(ErrorCode2FACodeSendFailed, "Failed to deliver 2FA code, try again later"),
 
 	// Session errors
 	ErrSessionBelongsToAnotherDevice: NewAPIError(ErrorCodeSessionWrongDevice, "Session belongs to another device"),
 	ErrSessionExpired:                NewAPIError(ErrorCodeSessionExpired, "Session has expired, please login again"),
 	ErrSessionNotFound:               NewAPIError(ErrorCodeSessionNotFound, "Session not found"),
 	ErrInvalidSessionID:              NewAPIError(ErrorCodeInvalidSessionID, "Invalid session ID"),
+	ErrTrustedDeviceNotFound:         NewAPIError(ErrorCodeTrustedDeviceNotFound, "Trusted device not found"),
+	ErrRefreshTokenReused:            NewAPIError(ErrorCodeRefreshTokenReused, "Refresh token was already used, all sessions of this login were revoked, please login again"),
 
 	// User errors
-	ErrEmailAlreadyTaken:        NewAPIError(ErrorCodeEmailTaken, "Email address is already taken"),
-	ErrUsernameAlreadyTaken:     NewAPIError(ErrorCodeUsernameTaken, "Username is already taken"),
-	ErrUserNotFound:             NewAPIError(ErrorCodeUserNotFound, "User not found"),
-	ErrInvalidEmailFormat:       NewAPIError(ErrorCodeInvalidEmailFormat, "Invalid email format"),
-	ErrCantChangePhone2FA:       NewAPIError(ErrorCodeCantChangePhone2FA, "Cannot change phone number while 2FA is active"),
-	ErrNoPhoneNumberToEnable2FA: NewAPIError(ErrorCode2FAPhoneNotSet, "you must set phone number before requesting codes to set 2FA"),
+	ErrEmailAlreadyTaken:             NewAPIError(ErrorCodeEmailTaken, "Email address is already taken"),
+	ErrUsernameAlreadyTaken:          NewAPIError(ErrorCodeUsernameTaken, "Username is already taken"),
+	ErrUserNotFound:                  NewAPIError(ErrorCodeUserNotFound, "User not found"),
+	ErrInvalidEmailFormat:            NewAPIError(ErrorCodeInvalidEmailFormat, "Invalid email format"),
+	ErrCantChangePhone2FA:            NewAPIError(ErrorCodeCantChangePhone2FA, "Cannot change phone number while 2FA is active"),
+	ErrNoPhoneNumberToEnable2FA:      NewAPIError(ErrorCode2FAPhoneNotSet, "you must set phone number before requesting codes to set 2FA"),
+	ErrEmailNotVerified:              NewAPIError(ErrorCodeEmailNotVerified, "Verify your email address to perform this action"),
+	ErrEmailAlreadyVerified:          NewAPIError(ErrorCodeEmailVerified, "Email address is already verified"),
+	ErrInvalidEmailVerificationToken: NewAPIError(ErrorCodeVerifyTokenInvalid, "Email verification link is invalid or has expired"),
+	ErrInvalidEmailChangeToken:       NewAPIError(ErrorCodeChangeTokenInvalid, "Email change link is invalid, expired or was superseded by a newer request"),
+	ErrCurrentPasswordInvalid:        NewAPIError(ErrorCodeCurrentPassword, "Current password is incorrect"),
+	ErrWeakPassword:                  NewAPIError(ErrorCodeWeakPassword, "Password must be at least 8 characters and contain upper and lower case letters, a digit and a special character"),
+
+	// Task errors
+	ErrTaskNotFound:            NewAPIError(ErrorCodeTaskNotFound, "Task not found"),
+	ErrSubTaskNotFound:         NewAPIError(ErrorCodeSubTaskNotFound, "Subtask not found"),
+	ErrInvalidTaskStatus:       NewAPIError(ErrorCodeInvalidTaskStatus, "Invalid task status"),
+	ErrInvalidStatusTransition: NewAPIError(ErrorCodeInvalidStatusTransition, "Task cannot be moved to this status from its current one"),
+	ErrInvalidTaskDates:        NewAPIError(ErrorCodeInvalidTaskDates, "start_at must be before due_at"),
+	ErrEmptyTitle:              NewAPIError(ErrorCodeEmptyTitle, "Title must not be blank"),
+
+	// List errors
+	ErrInvalidSortField:   NewAPIError(ErrorCodeInvalidSortField, "Unsupported sort field"),
+	ErrInvalidFilterValue: NewAPIError(ErrorCodeInvalidFilter, "Invalid filter value"),
+	ErrInvalidCursor:      NewAPIError(ErrorCodeInvalidCursor, "Invalid or expired pagination cursor"),
+	ErrEmptySearchQuery:   NewAPIError(ErrorCodeEmptySearchQuery, "Search query is empty"),
+
+	// Note errors
+	ErrNoteNotFound: NewAPIError(ErrorCodeNoteNotFound, "Note not found"),
+
+	// Tag errors
+	ErrTagNotFound:      NewAPIError(ErrorCodeTagNotFound, "Tag not found"),
+	ErrTagAlreadyExists: NewAPIError(ErrorCodeTagAlreadyExists, "Tag with this name already exists"),
+	ErrTagMergeIntoSelf: NewAPIError(ErrorCodeTagMergeIntoSelf, "Tag cannot be merged into itself"),
+	ErrEmptyTagName:     NewAPIError(ErrorCodeEmptyTagName, "Tag name must not be blank"),
 }
 
 func ConvertError(err error) error {
@@ -0,0 +1,41 @@
+package entities
+
+import "errors"
+
+var (
+	ErrInvalidSortField   = errors.New("invalid sort field")
+	ErrInvalidFilterValue = errors.New("invalid filter value")
+	ErrInvalidCursor      = errors.New("invalid pagination cursor")
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest - параметры курсорной пагинации из query (?cursor=&limit=&sort=)
+type PageRequest struct {
+	Cursor string
+	Limit  int
+	Sort   string
+}
+
+// NormalizedLimit ограничивает limit сверху MaxPageLimit
+func (p PageRequest) NormalizedLimit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return p.Limit
+	}
+}
+
+// Page - одна страница списка. NextCursor пустой, если дальше ничего нет
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Limit      int    `json:"limit"`
+}
@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAccountLocked  = errors.New("account temporarily locked")
+	ErrLoginThrottled = errors.New("too many failed login attempts")
+)
+
+// LoginBlock - до какого момента (unix ms) вход запрещен. Locked - полноценная блокировка
+// после исчерпания попыток, иначе это прогрессивная задержка между попытками.
+// Миллисекунды, потому что задержка в 1с при округлении до секунд часто пропадала
+type LoginBlock struct {
+	UntilMs int64 `json:"until_ms"`
+	Locked  bool  `json:"locked"`
+}
+
+// NewAccountLockedError - аккаунт заблокирован, в details время разблокировки
+func NewAccountLockedError(until time.Time) *APIError {
+	return NewAPIError(ErrorCodeAccountLocked, "Account is temporarily locked due to too many failed login attempts",
+		loginRetryDetails("locked_until", until))
+}
+
+// NewLoginThrottledError - слишком частые неудачные попытки, в details когда можно повторить
+func NewLoginThrottledError(retryAt time.Time) *APIError {
+	return NewAPIError(ErrorCodeLoginThrottled, "Too many failed login attempts, try again later",
+		loginRetryDetails("retry_at", retryAt))
+}
+
+func loginRetryDetails(field string, at time.Time) map[string]interface{} {
+	retryAfter := int(time.Until(at).Seconds()) + 1
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return map[string]interface{}{
+		field:         at.UTC().Format(time.RFC3339),
+		"retry_after": retryAfter,
+	}
+}
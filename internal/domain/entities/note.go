@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoteNotFound = errors.New("note not found")
+)
+
+type Note struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index;index:idx_notes_user_created,priority:1"`
+	Title     string    `json:"title" gorm:"not null"`
+	Body      string    `json:"body" gorm:"type:text;not null;default:''"` // Markdown
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_notes_user_created,priority:2"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	Tags      []Tag     `json:"tags" gorm:"many2many:note_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (Note) TableName() string {
+	return "notes"
+}
+
+func (u *Note) BeforeCreate(tx *gorm.DB) error {
+	u.ID = uuid.New()
+	u.CreatedAt = time.Now()
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (u *Note) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+type NoteCreateReq struct {
+	Title string `json:"title" validate:"required,min=1,max=255"`
+	Body  string `json:"body" validate:"max=100000"`
+}
+
+type NoteUpdateReq struct {
+	Title *string `json:"title" validate:"omitempty,min=1,max=255"`
+	Body  *string `json:"body" validate:"omitempty,max=100000"`
+}
+
+// NoteListFilter - фильтры для списка заметок
+type NoteListFilter struct {
+	Tags TagFilter
+}
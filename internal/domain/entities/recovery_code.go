@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecoveryCode - одноразовый код на случай потери телефона/приложения.
+// Сам код показываем пользователю один раз, в БД только argon2 хэш
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	User      *User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+func (u *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	u.ID = uuid.New()
+	u.CreatedAt = time.Now()
+	return nil
+}
+
+type RecoveryCodesRegenerateReq struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=128"`
+	// Код 2FA (или recovery код) из контекста reauth
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type RecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RecoveryCodesCountRes struct {
+	Remaining int64 `json:"remaining"`
+}
@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptySearchQuery = errors.New("search query is empty")
+)
+
+type SearchResultType string
+
+const (
+	SearchResultTask    SearchResultType = "task"
+	SearchResultSubTask SearchResultType = "subtask"
+	SearchResultNote    SearchResultType = "note"
+)
+
+// SearchQuery - разобранная строка поиска: Text уходит в websearch_to_tsquery
+// (там уже есть "фразы" и -исключения), Tags - из токенов tag:foo
+type SearchQuery struct {
+	Text string
+	Tags []string
+}
+
+type SearchResult struct {
+	Type      SearchResultType `json:"type"`
+	ID        uuid.UUID        `json:"id"`
+	TaskID    *uuid.UUID       `json:"task_id,omitempty"`
+	Title     string           `json:"title"`
+	Snippet   string           `json:"snippet"`
+	Rank      float64          `json:"rank"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type SearchRes struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
@@ -12,6 +12,7 @@ var (
 	ErrSessionExpired                = errors.New("session expired, please login")
 	ErrSessionNotFound               = errors.New("session not found")
 	ErrInvalidSessionID              = errors.New("invalid session ID")
+	ErrRefreshTokenReused            = errors.New("refresh token reuse detected")
 )
 
 type Session struct {
@@ -22,6 +23,28 @@ type Session struct {
 	UserAgent    string    `json:"user_agent" redis:"user_agent"`
 	IP           string    `json:"ip" redis:"ip"`
 	ExpiresAt    int64     `json:"expires_at" redis:"expires_at"`
+	CreatedAt    int64     `json:"created_at" redis:"created_at"`
+	FamilyID     string    `json:"family_id" redis:"family_id"`
+}
+
+// TokenFamily - цепочка сессий, полученных ротацией одного refresh токена.
+// Живой должна быть только CurrentSessionID, но при отзыве семейства
+// убиваем все SessionIDs на случай, если какая-то пережила ротацию
+type TokenFamily struct {
+	FamilyID         string    `json:"family_id"`
+	UserID           uuid.UUID `json:"user_id"`
+	CurrentSessionID string    `json:"current_session_id"`
+	SessionIDs       []string  `json:"session_ids"`
+	CreatedAt        int64     `json:"created_at"`
+}
+
+// RotatedRefreshToken - след уже использованного refresh токена,
+// повторное предъявление такого токена значит, что он утек
+type RotatedRefreshToken struct {
+	FamilyID  string    `json:"family_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	RotatedAt int64     `json:"rotated_at"`
 }
 
 func (s *Session) IsExpired() bool {
@@ -31,3 +54,13 @@ func (s *Session) IsExpired() bool {
 func (s *Session) IsValid(refreshToken string) bool {
 	return !s.IsExpired() && s.RefreshToken == refreshToken
 }
+
+// SessionRes - сессия для списка устройств, без токенов
+type SessionRes struct {
+	SessionID string `json:"session_id"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+	Current   bool   `json:"current"`
+}
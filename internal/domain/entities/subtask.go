@@ -1,19 +1,29 @@
 package entities
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrSubTaskNotFound = errors.New("subtask not found")
+)
+
 type SubTask struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Title       string    `json:"title" gorm:"not null"`
-	Description string    `json:"description" gorm:"not null"`
-	TaskID      uuid.UUID `json:"task_id" gorm:"type:uuid"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey"`
+	Title       string       `json:"title" gorm:"not null"`
+	Description string       `json:"description" gorm:"not null"`
+	TaskID      uuid.UUID    `json:"task_id" gorm:"type:uuid;not null;index"`
+	Priority    TaskPriority `json:"priority" gorm:"type:smallint;default:0;not null"`
+	StartAt     *time.Time   `json:"start_at"`
+	DueAt       *time.Time   `json:"due_at" gorm:"index"`
+	Completed   bool         `json:"completed" gorm:"default:false;not null"`
+	CompletedAt *time.Time   `json:"completed_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 }
 
 func (SubTask) TableName() string {
@@ -31,3 +41,22 @@ func (u *SubTask) BeforeUpdate(tx *gorm.DB) error {
 	u.UpdatedAt = time.Now()
 	return nil
 }
+
+type SubTaskCreateReq struct {
+	Title       string        `json:"title" validate:"required,min=1,max=255"`
+	Description string        `json:"description" validate:"max=10000"`
+	Priority    *TaskPriority `json:"priority"`
+	StartAt     *time.Time    `json:"start_at"`
+	DueAt       *time.Time    `json:"due_at"`
+}
+
+type SubTaskUpdateReq struct {
+	Title        *string       `json:"title" validate:"omitempty,min=1,max=255"`
+	Description  *string       `json:"description" validate:"omitempty,max=10000"`
+	Completed    *bool         `json:"completed"`
+	Priority     *TaskPriority `json:"priority"`
+	StartAt      *time.Time    `json:"start_at"`
+	DueAt        *time.Time    `json:"due_at"`
+	ClearStartAt bool          `json:"clear_start_at"`
+	ClearDueAt   bool          `json:"clear_due_at"`
+}
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagAlreadyExists = errors.New("tag with this name already exists")
+	ErrTagMergeIntoSelf = errors.New("tag cannot be merged into itself")
+	ErrEmptyTagName     = errors.New("tag name is empty")
+)
+
+// Tag - метка пользователя, общая для задач и заметок
+type Tag struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_tags_user_name,priority:1"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_tags_user_name,priority:2"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+func (u *Tag) BeforeCreate(tx *gorm.DB) error {
+	u.ID = uuid.New()
+	u.CreatedAt = time.Now()
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (u *Tag) BeforeUpdate(tx *gorm.DB) error {
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+type TagMatchMode string
+
+const (
+	// TagMatchAll - у объекта должны быть все перечисленные теги (AND)
+	TagMatchAll TagMatchMode = "all"
+	// TagMatchAny - хотя бы один из тегов (OR)
+	TagMatchAny TagMatchMode = "any"
+)
+
+func (m TagMatchMode) IsValid() bool {
+	return m == TagMatchAll || m == TagMatchAny
+}
+
+// TagFilter - фильтр списков задач и заметок по именам тегов
+type TagFilter struct {
+	Names []string
+	Mode  TagMatchMode
+}
+
+type TagCreateReq struct {
+	Name string `json:"name" validate:"required,min=1,max=50,excludesall=0x2C"`
+}
+
+type TagRenameReq struct {
+	Name string `json:"name" validate:"required,min=1,max=50,excludesall=0x2C"`
+}
+
+type TagMergeReq struct {
+	IntoID string `json:"into_id" validate:"required,uuid"`
+}
+
+// TagBulkReq - массовое прикрепление/открепление тегов к задачам и заметкам
+type TagBulkReq struct {
+	TagIDs  []uuid.UUID `json:"tag_ids" validate:"required,min=1,max=100"`
+	TaskIDs []uuid.UUID `json:"task_ids" validate:"max=500"`
+	NoteIDs []uuid.UUID `json:"note_ids" validate:"max=500"`
+}
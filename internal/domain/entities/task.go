@@ -1,21 +1,37 @@
 package entities
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrTaskNotFound            = errors.New("task not found")
+	ErrInvalidTaskStatus       = errors.New("invalid task status")
+	ErrInvalidStatusTransition = errors.New("invalid task status transition")
+	ErrInvalidTaskDates        = errors.New("start_at must be before due_at")
+	// Общая для задач, подзадач и заметок: required пропускает заголовок из одних пробелов
+	ErrEmptyTitle = errors.New("title is empty")
+)
+
 type Task struct {
-	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	User        User      `json:"user" gorm:"foreignKey:UserID"`
-	UserID      uuid.UUID `json:"task_id" gorm:"type:uuid;not null"`
-	Title       string    `json:"title" gorm:"not null"`
-	Description string    `json:"description" gorm:"not null"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	SubTasks    []SubTask `json:"sub_tasks" gorm:"foreignKey:TaskID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ID          uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;index:idx_tasks_user_priority_keyset,priority:4;index:idx_tasks_user_due_keyset,priority:4;index:idx_tasks_user_due_desc_keyset,priority:4,sort:desc"`
+	User        *User        `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	UserID      uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index;index:idx_tasks_user_priority_keyset,priority:1;index:idx_tasks_user_due_keyset,priority:1;index:idx_tasks_user_due_desc_keyset,priority:1"`
+	Title       string       `json:"title" gorm:"not null"`
+	Description string       `json:"description" gorm:"not null"`
+	Status      TaskStatus   `json:"status" gorm:"type:varchar(20);default:'todo';not null;index"`
+	Priority    TaskPriority `json:"priority" gorm:"type:smallint;default:0;not null;index:idx_tasks_user_priority_keyset,priority:2"`
+	StartAt     *time.Time   `json:"start_at"`
+	DueAt       *time.Time   `json:"due_at" gorm:"index:idx_tasks_user_due_keyset,priority:2;index:idx_tasks_user_due_desc_keyset,priority:2,sort:desc nulls last"`
+	CompletedAt *time.Time   `json:"completed_at"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"autoCreateTime;index:idx_tasks_user_priority_keyset,priority:3;index:idx_tasks_user_due_keyset,priority:3;index:idx_tasks_user_due_desc_keyset,priority:3,sort:desc"`
+	UpdatedAt   time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+	SubTasks    []SubTask    `json:"sub_tasks" gorm:"foreignKey:TaskID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Tags        []Tag        `json:"tags" gorm:"many2many:task_tags;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
 func (Task) TableName() string {
@@ -24,6 +40,9 @@ func (Task) TableName() string {
 
 func (u *Task) BeforeCreate(tx *gorm.DB) error {
 	u.ID = uuid.New()
+	if u.Status == "" {
+		u.Status = TaskStatusTodo
+	}
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
 	return nil
@@ -33,3 +52,36 @@ func (u *Task) BeforeUpdate(tx *gorm.DB) error {
 	u.UpdatedAt = time.Now()
 	return nil
 }
+
+type TaskCreateReq struct {
+	Title       string        `json:"title" validate:"required,min=1,max=255"`
+	Description string        `json:"description" validate:"max=10000"`
+	Priority    *TaskPriority `json:"priority"`
+	StartAt     *time.Time    `json:"start_at"`
+	DueAt       *time.Time    `json:"due_at"`
+}
+
+// TaskUpdateReq - частичное обновление, nil поля не трогаем
+type TaskUpdateReq struct {
+	Title        *string       `json:"title" validate:"omitempty,min=1,max=255"`
+	Description  *string       `json:"description" validate:"omitempty,max=10000"`
+	Priority     *TaskPriority `json:"priority"`
+	StartAt      *time.Time    `json:"start_at"`
+	DueAt        *time.Time    `json:"due_at"`
+	ClearStartAt bool          `json:"clear_start_at"`
+	ClearDueAt   bool          `json:"clear_due_at"`
+}
+
+// TaskListFilter - фильтры и сортировка для списка задач
+type TaskListFilter struct {
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Overdue    bool
+	Priorities []TaskPriority
+	Tags       TagFilter
+}
+
+type TaskTransitionReq struct {
+	Status           TaskStatus `json:"status" validate:"required"`
+	CompleteSubTasks bool       `json:"complete_subtasks"`
+}
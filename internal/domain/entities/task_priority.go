@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TaskPriority хранится числом, чтобы сортировка в БД шла по важности,
+// а наружу отдается строкой
+type TaskPriority int16
+
+const (
+	TaskPriorityNone TaskPriority = iota
+	TaskPriorityLow
+	TaskPriorityMedium
+	TaskPriorityHigh
+	TaskPriorityUrgent
+)
+
+var taskPriorityNames = map[TaskPriority]string{
+	TaskPriorityNone:   "none",
+	TaskPriorityLow:    "low",
+	TaskPriorityMedium: "medium",
+	TaskPriorityHigh:   "high",
+	TaskPriorityUrgent: "urgent",
+}
+
+func (p TaskPriority) String() string {
+	if name, ok := taskPriorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("TaskPriority(%d)", int16(p))
+}
+
+func (p TaskPriority) IsValid() bool {
+	_, ok := taskPriorityNames[p]
+	return ok
+}
+
+func ParseTaskPriority(s string) (TaskPriority, error) {
+	for p, name := range taskPriorityNames {
+		if name == s {
+			return p, nil
+		}
+	}
+	return TaskPriorityNone, fmt.Errorf("invalid task priority: %s", s)
+}
+
+func (p TaskPriority) MarshalJSON() ([]byte, error) {
+	if !p.IsValid() {
+		return nil, fmt.Errorf("invalid task priority: %d", int16(p))
+	}
+	return json.Marshal(p.String())
+}
+
+func (p *TaskPriority) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseTaskPriority(s)
+	if err != nil {
+		return err
+	}
+
+	*p = parsed
+	return nil
+}
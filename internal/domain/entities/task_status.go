@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type TaskStatus string
+
+const (
+	TaskStatusTodo       TaskStatus = "todo"
+	TaskStatusInProgress TaskStatus = "in_progress"
+	TaskStatusBlocked    TaskStatus = "blocked"
+	TaskStatusDone       TaskStatus = "done"
+	TaskStatusCancelled  TaskStatus = "cancelled"
+)
+
+// taskStatusTransitions - разрешенные переходы из каждого статуса
+var taskStatusTransitions = map[TaskStatus][]TaskStatus{
+	TaskStatusTodo:       {TaskStatusInProgress, TaskStatusBlocked, TaskStatusDone, TaskStatusCancelled},
+	TaskStatusInProgress: {TaskStatusTodo, TaskStatusBlocked, TaskStatusDone, TaskStatusCancelled},
+	TaskStatusBlocked:    {TaskStatusTodo, TaskStatusInProgress, TaskStatusCancelled},
+	TaskStatusDone:       {TaskStatusTodo, TaskStatusInProgress},
+	TaskStatusCancelled:  {TaskStatusTodo},
+}
+
+func (s TaskStatus) String() string {
+	return string(s)
+}
+
+func (s TaskStatus) IsValid() bool {
+	switch s {
+	case TaskStatusTodo, TaskStatusInProgress, TaskStatusBlocked, TaskStatusDone, TaskStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s TaskStatus) CanTransitionTo(next TaskStatus) bool {
+	for _, allowed := range taskStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s TaskStatus) Value() (driver.Value, error) {
+	if !s.IsValid() {
+		return nil, fmt.Errorf("invalid task status: %s", s)
+	}
+	return string(s), nil
+}
+
+func (s *TaskStatus) Scan(value interface{}) error {
+	if value == nil {
+		*s = TaskStatusTodo
+		return nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		*s = TaskStatus(v)
+	case []byte:
+		*s = TaskStatus(v)
+	default:
+		return fmt.Errorf("cannot scan %T into TaskStatus", value)
+	}
+
+	if !s.IsValid() {
+		return fmt.Errorf("invalid task status: %s", *s)
+	}
+
+	return nil
+}
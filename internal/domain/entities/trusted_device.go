@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTrustedDeviceNotFound = errors.New("trusted device not found")
+)
+
+// TrustedDevice - браузер, на котором пользователь попросил не спрашивать 2FA.
+// Хранится в Redis, в cookie лежит только подписанный идентификатор
+type TrustedDevice struct {
+	DeviceID   string    `json:"device_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  int64     `json:"created_at"`
+	LastUsedAt int64     `json:"last_used_at"`
+	ExpiresAt  int64     `json:"expires_at"`
+}
+
+type TrustedDeviceRes struct {
+	DeviceID   string `json:"device_id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
@@ -15,19 +15,30 @@ var (
 	ErrInvalidEmailFormat       = errors.New("invalid email format")
 	ErrCantChangePhone2FA       = errors.New("you cant change phone number while 2FA active")
 	ErrNoPhoneNumberToEnable2FA = errors.New("you must set phone number before requesting codes to set 2FA")
+	ErrWeakPassword             = errors.New("password is too weak")
+	ErrCurrentPasswordInvalid   = errors.New("current password is invalid")
 )
 
 type User struct {
-	ID               uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Username         string    `json:"username" gorm:"unique;not null"`
-	Email            string    `json:"email" gorm:"unique;not null"`
-	PhoneNumber      string    `json:"phone_number" gorm:"unique"`
-	TwoFactorEnabled bool      `json:"two_factor_enabled" gorm:"default:false"`
-	Password         string    `json:"-" gorm:"not null"`
-	Role             RoleType  `json:"role" gorm:"default:'user';not null"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	Tasks            []Task    `json:"tasks" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	Username        string     `json:"username" gorm:"unique;not null"`
+	Email           string     `json:"email" gorm:"unique;not null"`
+	PhoneNumber     string     `json:"phone_number" gorm:"unique"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Пользователь запросил удаление, до окончания grace периода вход его отменяет
+	DeletionRequestedAt *time.Time      `json:"deletion_requested_at" gorm:"index"`
+	TwoFactorEnabled    bool            `json:"two_factor_enabled" gorm:"default:false"`
+	TwoFactorMethod     TwoFactorMethod `json:"two_factor_method" gorm:"type:varchar(16);default:'telegram';not null"`
+	TOTPSecret          string          `json:"-" gorm:"column:totp_secret"`
+	Password            string          `json:"-" gorm:"not null"`
+	Role                RoleType        `json:"role" gorm:"default:'user';not null"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	Tasks               []Task          `json:"tasks" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 func (User) TableName() string {
@@ -39,6 +50,7 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
 	u.TwoFactorEnabled = false
+	u.TwoFactorMethod = TwoFactorMethodTelegram
 	return nil
 }
 
@@ -47,10 +59,40 @@ func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
+// UsesTOTP - код берем из приложения-аутентификатора, а не отправляем в Telegram
+func (u *User) UsesTOTP() bool {
+	return u.TwoFactorMethod == TwoFactorMethodTOTP && u.TOTPSecret != ""
+}
+
 type UserUpdatePhoneReq struct {
 	PhoneNumber string `json:"phone_number" validate:"required,phone"`
 }
 
+type UserDeleteAccountReq struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=128"`
+	// Код 2FA (или recovery код), обязателен если 2FA включена
+	Code string `json:"code" validate:"omitempty,max=32"`
+}
+
+type UserDeleteAccountRes struct {
+	Message             string    `json:"message"`
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+type UserChangeUsernameReq struct {
+	Username string `json:"username" validate:"required,min=3,max=50,alphanum"`
+}
+
+type UserChangePasswordReq struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=128"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=128"`
+	// Код 2FA (или recovery код), обязателен если 2FA включена
+	Code                 string `json:"code" validate:"omitempty,max=32"`
+	SignOutOtherSessions bool   `json:"sign_out_other_sessions"`
+}
+
 type Verify2FACodeReq struct {
-	Code string `json:"code" validate:"required"`
+	Code string `json:"code" param:"code" validate:"required"`
+	// Запомнить браузер и не спрашивать 2FA при следующих входах
+	RememberDevice bool `json:"remember_device"`
 }
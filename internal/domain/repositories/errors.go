@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation - SQLSTATE нарушения уникального индекса
+const pgUniqueViolation = "23505"
+
+// isUniqueViolation - проверки на существование в репозиториях не атомарны,
+// параллельная вставка все равно упирается в уникальный индекс
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"errors"
+	"rest-api-notes/internal/domain/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// noteSortFields - белый список сортировок для списка заметок
+var noteSortFields = SortFields{
+	"created_at":  {Column: "created_at"},
+	"-created_at": {Column: "created_at", Desc: true},
+	"updated_at":  {Column: "updated_at"},
+	"-updated_at": {Column: "updated_at", Desc: true},
+	"title":       {Column: "title", Type: SortValueString},
+	"-title":      {Column: "title", Desc: true, Type: SortValueString},
+}
+
+func noteCursorKey(note *entities.Note, column string) (interface{}, time.Time, uuid.UUID) {
+	var value interface{}
+	switch column {
+	case "updated_at":
+		value = note.UpdatedAt
+	case "title":
+		value = note.Title
+	}
+	return value, note.CreatedAt, note.ID
+}
+
+type noteRepository struct {
+	db        *gorm.DB
+	paginator *Paginator
+}
+
+type NoteRepository interface {
+	Create(note *entities.Note) error
+	GetByID(noteID, userID uuid.UUID) (*entities.Note, error)
+	List(userID uuid.UUID, filter *entities.NoteListFilter, page entities.PageRequest) (*entities.Page[entities.Note], error)
+	Update(noteID, userID uuid.UUID, updates map[string]interface{}) error
+	Delete(noteID, userID uuid.UUID) error
+}
+
+func NewNoteRepository(db *gorm.DB, paginator *Paginator) NoteRepository {
+	return &noteRepository{db: db, paginator: paginator}
+}
+
+func (r *noteRepository) Create(note *entities.Note) error {
+	return r.db.Create(note).Error
+}
+
+func (r *noteRepository) GetByID(noteID, userID uuid.UUID) (*entities.Note, error) {
+	var note entities.Note
+	if err := r.db.Preload("Tags").Where("id = ? AND user_id = ?", noteID, userID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrNoteNotFound
+		}
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+func (r *noteRepository) List(userID uuid.UUID, filter *entities.NoteListFilter, page entities.PageRequest) (*entities.Page[entities.Note], error) {
+	query := r.db.Where("user_id = ?", userID)
+	query = applyTagFilter(query, "note_tags", "note_id", userID, filter.Tags)
+
+	return Paginate(r.paginator, query.Preload("Tags"), page, noteSortFields, "-created_at", noteCursorKey)
+}
+
+func (r *noteRepository) Update(noteID, userID uuid.UUID, updates map[string]interface{}) error {
+	result := r.db.Model(&entities.Note{}).Where("id = ? AND user_id = ?", noteID, userID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrNoteNotFound
+	}
+	return nil
+}
+
+func (r *noteRepository) Delete(noteID, userID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"DELETE FROM note_tags WHERE note_id IN (SELECT id FROM notes WHERE id = ? AND user_id = ?)",
+			noteID, userID).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", noteID, userID).Delete(&entities.Note{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entities.ErrNoteNotFound
+		}
+		return nil
+	})
+}
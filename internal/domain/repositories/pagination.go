@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"rest-api-notes/internal/domain/entities"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SortValueType - тип значения колонки сортировки, нужен чтобы
+// восстановить значение из курсора с правильным типом для pgx
+type SortValueType int
+
+const (
+	SortValueTime SortValueType = iota
+	SortValueInt
+	SortValueString
+)
+
+// SortField - одна разрешенная сортировка. Внутри одинакового значения
+// порядок всегда добивается парой (created_at, id) в том же направлении
+type SortField struct {
+	Column   string
+	Desc     bool
+	Nullable bool
+	Type     SortValueType
+}
+
+// SortFields - белый список сортировок, ключ - значение ?sort= (например "-priority")
+type SortFields map[string]SortField
+
+// CursorKeyFunc достает из элемента значения для курсора:
+// значение колонки сортировки (nil для NULL), created_at и id
+type CursorKeyFunc[T any] func(item *T, column string) (value interface{}, createdAt time.Time, id uuid.UUID)
+
+// Paginator подписывает курсоры, чтобы клиент не мог подсунуть свои значения
+type Paginator struct {
+	secret []byte
+}
+
+func NewPaginator(secret string) *Paginator {
+	return &Paginator{secret: []byte(secret)}
+}
+
+type cursor struct {
+	Sort      string    `json:"s"`
+	Value     *string   `json:"v,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// Paginate применяет сортировку, курсор и limit к query и собирает страницу.
+// query уже должен содержать все фильтры (в том числе по user_id)
+func Paginate[T any](p *Paginator, query *gorm.DB, req entities.PageRequest,
+	sorts SortFields, defaultSort string, key CursorKeyFunc[T]) (*entities.Page[T], error) {
+	//
+	sortKey := req.Sort
+	if sortKey == "" {
+		sortKey = defaultSort
+	}
+	field, ok := sorts[sortKey]
+	if !ok {
+		return nil, entities.ErrInvalidSortField
+	}
+
+	if req.Cursor != "" {
+		c, err := p.decode(req.Cursor)
+		if err != nil || c.Sort != sortKey {
+			return nil, entities.ErrInvalidCursor
+		}
+
+		condition, args, err := field.keysetCondition(c)
+		if err != nil {
+			return nil, entities.ErrInvalidCursor
+		}
+		query = query.Where(condition, args...)
+	}
+
+	limit := req.NormalizedLimit()
+	items := []T{}
+	if err := query.Order(field.orderClause()).Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	page := &entities.Page[T]{Limit: limit}
+	if len(items) > limit {
+		items = items[:limit]
+		page.HasMore = true
+
+		value, createdAt, id := key(&items[len(items)-1], field.Column)
+		formatted, err := field.formatValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := p.encode(&cursor{Sort: sortKey, Value: formatted, CreatedAt: createdAt, ID: id})
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	page.Data = items
+
+	return page, nil
+}
+
+func (f SortField) direction() (string, string) {
+	if f.Desc {
+		return "DESC", "<"
+	}
+	return "ASC", ">"
+}
+
+func (f SortField) orderClause() string {
+	dir, _ := f.direction()
+	if f.Column == "created_at" {
+		return fmt.Sprintf("created_at %[1]s, id %[1]s", dir)
+	}
+
+	nulls := ""
+	if f.Nullable {
+		nulls = " NULLS LAST"
+	}
+	return fmt.Sprintf("%[2]s %[1]s%[3]s, created_at %[1]s, id %[1]s", dir, f.Column, nulls)
+}
+
+// keysetCondition строит условие "после курсора" для текущего порядка.
+// NULL всегда в конце, поэтому для nullable колонок отдельные ветки
+func (f SortField) keysetCondition(c *cursor) (string, []interface{}, error) {
+	_, op := f.direction()
+
+	if f.Column == "created_at" {
+		return fmt.Sprintf("(created_at, id) %s (?, ?)", op), []interface{}{c.CreatedAt, c.ID}, nil
+	}
+
+	if c.Value == nil {
+		if !f.Nullable {
+			return "", nil, entities.ErrInvalidCursor
+		}
+		return fmt.Sprintf("%s IS NULL AND (created_at, id) %s (?, ?)", f.Column, op),
+			[]interface{}{c.CreatedAt, c.ID}, nil
+	}
+
+	value, err := f.parseValue(*c.Value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if f.Nullable {
+		return fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND (created_at, id) %[2]s (?, ?)) OR %[1]s IS NULL)", f.Column, op),
+			[]interface{}{value, value, c.CreatedAt, c.ID}, nil
+	}
+
+	return fmt.Sprintf("(%s, created_at, id) %s (?, ?, ?)", f.Column, op),
+		[]interface{}{value, c.CreatedAt, c.ID}, nil
+}
+
+func (f SortField) formatValue(value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	var s string
+	switch v := value.(type) {
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339Nano)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("unsupported cursor value type %T", value)
+	}
+	return &s, nil
+}
+
+func (f SortField) parseValue(s string) (interface{}, error) {
+	switch f.Type {
+	case SortValueTime:
+		return time.Parse(time.RFC3339Nano, s)
+	case SortValueInt:
+		return strconv.ParseInt(s, 10, 64)
+	default:
+		return s, nil
+	}
+}
+
+// encode: base64url(json) + "." + base64url(hmac-sha256)
+func (p *Paginator) encode(c *cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + p.sign(payload), nil
+}
+
+func (p *Paginator) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (p *Paginator) decode(token string) (*cursor, error) {
+	payloadPart, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	if !hmac.Equal([]byte(p.sign(payload)), []byte(signature)) {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, entities.ErrInvalidCursor
+	}
+	return &c, nil
+}
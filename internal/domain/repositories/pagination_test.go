@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"errors"
+	"rest-api-notes/internal/domain/entities"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestPaginatorCursorRoundTrip(t *testing.T) {
+	p := NewPaginator("cursor-secret")
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor cursor
+	}{
+		{"created_at only", cursor{Sort: "-created_at", CreatedAt: createdAt, ID: uuid.New()}},
+		{"int value", cursor{Sort: "priority", Value: strPtr("3"), CreatedAt: createdAt, ID: uuid.New()}},
+		{"time value", cursor{Sort: "-due_at", Value: strPtr("2024-06-01T00:00:00Z"), CreatedAt: createdAt, ID: uuid.New()}},
+		{"null value", cursor{Sort: "due_at", CreatedAt: createdAt, ID: uuid.New()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := p.encode(&tt.cursor)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			got, err := p.decode(token)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Sort != tt.cursor.Sort || got.ID != tt.cursor.ID || !got.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Fatalf("decode: got %+v, want %+v", got, tt.cursor)
+			}
+			if (got.Value == nil) != (tt.cursor.Value == nil) || (got.Value != nil && *got.Value != *tt.cursor.Value) {
+				t.Fatalf("decode: got value %v, want %v", got.Value, tt.cursor.Value)
+			}
+		})
+	}
+}
+
+func TestPaginatorDecodeRejectsTampering(t *testing.T) {
+	p := NewPaginator("cursor-secret")
+	token, err := p.encode(&cursor{Sort: "priority", Value: strPtr("1"), CreatedAt: time.Now(), ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"priority","v":"100","c":"2024-01-01T00:00:00Z","i":"` + uuid.NewString() + `"}`))
+	otherKey, err := NewPaginator("other-secret").encode(&cursor{Sort: "priority", CreatedAt: time.Now(), ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", payload},
+		{"empty signature", payload + "."},
+		{"payload not base64", "!!!." + signature},
+		{"forged payload", forged + "." + signature},
+		{"signed with another secret", otherKey},
+		{"signature of another payload", payload + "." + strings.Split(otherKey, ".")[1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.decode(tt.token); !errors.Is(err, entities.ErrInvalidCursor) {
+				t.Fatalf("decode(%q): got error %v, want ErrInvalidCursor", tt.token, err)
+			}
+		})
+	}
+}
+
+func TestSortFieldValueRoundTrip(t *testing.T) {
+	due := time.Date(2024, 6, 1, 8, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name  string
+		field SortField
+		value interface{}
+		want  interface{}
+	}{
+		{"int", SortField{Column: "priority", Type: SortValueInt}, int64(2), int64(2)},
+		{"time", SortField{Column: "due_at", Nullable: true}, due, due},
+		{"string", SortField{Column: "title", Type: SortValueString}, "abc", "abc"},
+		{"null", SortField{Column: "due_at", Nullable: true}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := tt.field.formatValue(tt.value)
+			if err != nil {
+				t.Fatalf("formatValue: %v", err)
+			}
+			if formatted == nil {
+				if tt.want != nil {
+					t.Fatalf("formatValue: got nil, want %v", tt.want)
+				}
+				return
+			}
+
+			got, err := tt.field.parseValue(*formatted)
+			if err != nil {
+				t.Fatalf("parseValue: %v", err)
+			}
+			if gotTime, ok := got.(time.Time); ok {
+				if !gotTime.Equal(tt.want.(time.Time)) {
+					t.Fatalf("parseValue: got %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Fatalf("parseValue: got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortFieldKeysetConditionNullCursor(t *testing.T) {
+	c := &cursor{CreatedAt: time.Now(), ID: uuid.New()}
+
+	tests := []struct {
+		name    string
+		field   SortField
+		wantErr bool
+		want    string
+	}{
+		{"nullable column", SortField{Column: "due_at", Nullable: true}, false, "due_at IS NULL AND (created_at, id) > (?, ?)"},
+		{"nullable column desc", SortField{Column: "due_at", Desc: true, Nullable: true}, false, "due_at IS NULL AND (created_at, id) < (?, ?)"},
+		{"not nullable column", SortField{Column: "priority", Type: SortValueInt}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := tt.field.keysetCondition(c)
+			if tt.wantErr {
+				if !errors.Is(err, entities.ErrInvalidCursor) {
+					t.Fatalf("keysetCondition: got error %v, want ErrInvalidCursor", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("keysetCondition: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("keysetCondition: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
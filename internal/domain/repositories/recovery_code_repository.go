@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"rest-api-notes/internal/domain/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+type RecoveryCodeRepository interface {
+	// Replace удаляет старый набор и сохраняет новый в одной транзакции
+	Replace(userID uuid.UUID, hashes []string) error
+	ListUnused(userID uuid.UUID) ([]entities.RecoveryCode, error)
+	CountUnused(userID uuid.UUID) (int64, error)
+	// MarkUsed возвращает false, если код уже успели использовать параллельно
+	MarkUsed(codeID uuid.UUID) (bool, error)
+	DeleteAll(userID uuid.UUID) error
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+func (r *recoveryCodeRepository) Replace(userID uuid.UUID, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]entities.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, entities.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *recoveryCodeRepository) ListUnused(userID uuid.UUID) ([]entities.RecoveryCode, error) {
+	var codes []entities.RecoveryCode
+	if err := r.db.Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (r *recoveryCodeRepository) CountUnused(userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.Model(&entities.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *recoveryCodeRepository) MarkUsed(codeID uuid.UUID) (bool, error) {
+	result := r.db.Model(&entities.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", codeID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *recoveryCodeRepository) DeleteAll(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error
+}
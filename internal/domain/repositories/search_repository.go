@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"fmt"
+	"html"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/database"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ts_headline не экранирует исходный текст, поэтому совпадения размечаем
+// символами из Private Use Area, а <mark> подставляем после html.EscapeString
+const (
+	searchHighlightStart = "\uE000"
+	searchHighlightStop  = "\uE001"
+)
+
+var searchHeadlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=35, MinWords=15, MaxFragments=2",
+	searchHighlightStart, searchHighlightStop)
+
+var searchHighlightReplacer = strings.NewReplacer(searchHighlightStart, "<mark>", searchHighlightStop, "</mark>")
+
+type searchRepository struct {
+	db *gorm.DB
+}
+
+type SearchRepository interface {
+	Search(userID uuid.UUID, query *entities.SearchQuery, limit int) ([]entities.SearchResult, error)
+}
+
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+// Search ищет по задачам, подзадачам и заметкам пользователя одним UNION ALL.
+// Подзадачи своих тегов не имеют, для tag: фильтра берутся теги родительской задачи
+func (r *searchRepository) Search(userID uuid.UUID, query *entities.SearchQuery, limit int) ([]entities.SearchResult, error) {
+	hasText := query.Text != ""
+
+	sources := []struct {
+		kind      entities.SearchResultType
+		from      string
+		alias     string
+		taskID    string
+		body      string
+		tagFilter string
+	}{
+		{entities.SearchResultTask, "tasks t", "t", "NULL::uuid", "t.description",
+			"t.id IN (" + tagSubQuery("task_tags", "task_id") + ")"},
+		{entities.SearchResultSubTask, "sub_tasks s JOIN tasks t ON t.id = s.task_id", "s", "s.task_id", "s.description",
+			"t.id IN (" + tagSubQuery("task_tags", "task_id") + ")"},
+		{entities.SearchResultNote, "notes n", "n", "NULL::uuid", "n.body",
+			"n.id IN (" + tagSubQuery("note_tags", "note_id") + ")"},
+	}
+
+	owner := map[entities.SearchResultType]string{
+		entities.SearchResultTask:    "t.user_id",
+		entities.SearchResultSubTask: "t.user_id",
+		entities.SearchResultNote:    "n.user_id",
+	}
+
+	selects := make([]string, 0, len(sources))
+	for _, source := range sources {
+		snippet := fmt.Sprintf("left(%s, 200)", source.body)
+		rank := "0::real"
+		conditions := []string{owner[source.kind] + " = @user_id"}
+
+		if hasText {
+			snippet = fmt.Sprintf("ts_headline('%s', coalesce(nullif(%s, ''), %s.title), q.query, '%s')",
+				database.SearchConfig, source.body, source.alias, searchHeadlineOptions)
+			rank = fmt.Sprintf("ts_rank(%s.search_vector, q.query)", source.alias)
+			conditions = append(conditions, source.alias+".search_vector @@ q.query")
+		}
+		if len(query.Tags) > 0 {
+			conditions = append(conditions, source.tagFilter)
+		}
+
+		from := source.from
+		if hasText {
+			from += " CROSS JOIN q"
+		}
+
+		selects = append(selects, fmt.Sprintf(
+			"SELECT '%s' AS type, %[2]s.id, %[3]s AS task_id, %[2]s.title, %[4]s AS snippet, %[5]s AS rank, %[2]s.created_at FROM %[6]s WHERE %[7]s",
+			source.kind, source.alias, source.taskID, snippet, rank, from, strings.Join(conditions, " AND ")))
+	}
+
+	sql := strings.Join(selects, " UNION ALL ") + " ORDER BY rank DESC, created_at DESC LIMIT @limit"
+	if hasText {
+		sql = fmt.Sprintf("WITH q AS (SELECT websearch_to_tsquery('%s', @text) AS query) ", database.SearchConfig) + sql
+	}
+
+	args := map[string]interface{}{
+		"user_id":   userID,
+		"text":      query.Text,
+		"tags":      query.Tags,
+		"tag_count": len(query.Tags),
+		"limit":     limit,
+	}
+
+	results := []entities.SearchResult{}
+	if err := r.db.Raw(sql, args).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+
+	return results, nil
+}
+
+// tagSubQuery - id объектов, у которых есть все теги из @tags (AND)
+func tagSubQuery(joinTable, column string) string {
+	return fmt.Sprintf(`SELECT jt.%[2]s FROM %[1]s jt JOIN tags g ON g.id = jt.tag_id
+		WHERE g.user_id = @user_id AND g.name IN @tags
+		GROUP BY jt.%[2]s HAVING COUNT(DISTINCT g.id) = @tag_count`, joinTable, column)
+}
+
+// highlightSnippet делает сниппет безопасным HTML, в котором размечены только совпадения
+func highlightSnippet(snippet string) string {
+	return searchHighlightReplacer.Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,218 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"rest-api-notes/internal/domain/entities"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+type TagRepository interface {
+	Create(tag *entities.Tag) error
+	GetByID(tagID, userID uuid.UUID) (*entities.Tag, error)
+	GetByName(name string, userID uuid.UUID) (*entities.Tag, error)
+	List(userID uuid.UUID) ([]entities.Tag, error)
+	Rename(tagID, userID uuid.UUID, name string) error
+	Delete(tagID, userID uuid.UUID) error
+	// MoveAssociations переносит все связи source на target, дубликаты пропускаются
+	MoveAssociations(sourceID, targetID uuid.UUID) error
+
+	CountUserTags(userID uuid.UUID, tagIDs []uuid.UUID) (int64, error)
+	CountUserTasks(userID uuid.UUID, taskIDs []uuid.UUID) (int64, error)
+	CountUserNotes(userID uuid.UUID, noteIDs []uuid.UUID) (int64, error)
+
+	AttachToTasks(userID uuid.UUID, tagIDs, taskIDs []uuid.UUID) error
+	DetachFromTasks(userID uuid.UUID, tagIDs, taskIDs []uuid.UUID) error
+	AttachToNotes(userID uuid.UUID, tagIDs, noteIDs []uuid.UUID) error
+	DetachFromNotes(userID uuid.UUID, tagIDs, noteIDs []uuid.UUID) error
+
+	Transaction(fn func(repo TagRepository) error) error
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) Create(tag *entities.Tag) error {
+	if _, err := r.GetByName(tag.Name, tag.UserID); err == nil {
+		return entities.ErrTagAlreadyExists
+	} else if !errors.Is(err, entities.ErrTagNotFound) {
+		return err
+	}
+
+	if err := r.db.Create(tag).Error; err != nil {
+		if isUniqueViolation(err) {
+			return entities.ErrTagAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *tagRepository) GetByID(tagID, userID uuid.UUID) (*entities.Tag, error) {
+	var tag entities.Tag
+	if err := r.db.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (r *tagRepository) GetByName(name string, userID uuid.UUID) (*entities.Tag, error) {
+	var tag entities.Tag
+	if err := r.db.Where("name = ? AND user_id = ?", name, userID).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (r *tagRepository) List(userID uuid.UUID) ([]entities.Tag, error) {
+	tags := []entities.Tag{}
+	if err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (r *tagRepository) Rename(tagID, userID uuid.UUID, name string) error {
+	if existing, err := r.GetByName(name, userID); err == nil && existing.ID != tagID {
+		return entities.ErrTagAlreadyExists
+	} else if err != nil && !errors.Is(err, entities.ErrTagNotFound) {
+		return err
+	}
+
+	result := r.db.Model(&entities.Tag{}).Where("id = ? AND user_id = ?", tagID, userID).Update("name", name)
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return entities.ErrTagAlreadyExists
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrTagNotFound
+	}
+	return nil
+}
+
+func (r *tagRepository) Delete(tagID, userID uuid.UUID) error {
+	if err := r.db.Exec("DELETE FROM task_tags WHERE tag_id = ?", tagID).Error; err != nil {
+		return err
+	}
+	if err := r.db.Exec("DELETE FROM note_tags WHERE tag_id = ?", tagID).Error; err != nil {
+		return err
+	}
+
+	result := r.db.Where("id = ? AND user_id = ?", tagID, userID).Delete(&entities.Tag{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrTagNotFound
+	}
+	return nil
+}
+
+func (r *tagRepository) MoveAssociations(sourceID, targetID uuid.UUID) error {
+	for _, join := range []struct{ table, column string }{
+		{"task_tags", "task_id"},
+		{"note_tags", "note_id"},
+	} {
+		move := fmt.Sprintf(
+			"INSERT INTO %[1]s (%[2]s, tag_id) SELECT %[2]s, ? FROM %[1]s WHERE tag_id = ? ON CONFLICT DO NOTHING",
+			join.table, join.column)
+		if err := r.db.Exec(move, targetID, sourceID).Error; err != nil {
+			return err
+		}
+
+		if err := r.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE tag_id = ?", join.table), sourceID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *tagRepository) CountUserTags(userID uuid.UUID, tagIDs []uuid.UUID) (int64, error) {
+	return r.countOwned(&entities.Tag{}, userID, tagIDs)
+}
+
+func (r *tagRepository) CountUserTasks(userID uuid.UUID, taskIDs []uuid.UUID) (int64, error) {
+	return r.countOwned(&entities.Task{}, userID, taskIDs)
+}
+
+func (r *tagRepository) CountUserNotes(userID uuid.UUID, noteIDs []uuid.UUID) (int64, error) {
+	return r.countOwned(&entities.Note{}, userID, noteIDs)
+}
+
+func (r *tagRepository) countOwned(model interface{}, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(model).Where("user_id = ? AND id IN ?", userID, ids).Count(&count).Error
+	return count, err
+}
+
+// Прикрепление делается одним INSERT ... SELECT, условие по user_id
+// не даст связать чужие задачи/заметки с чужими тегами
+
+func (r *tagRepository) AttachToTasks(userID uuid.UUID, tagIDs, taskIDs []uuid.UUID) error {
+	return r.db.Exec(`INSERT INTO task_tags (task_id, tag_id)
+		SELECT tasks.id, tags.id FROM tasks CROSS JOIN tags
+		WHERE tasks.id IN ? AND tags.id IN ? AND tasks.user_id = ? AND tags.user_id = ?
+		ON CONFLICT DO NOTHING`, taskIDs, tagIDs, userID, userID).Error
+}
+
+func (r *tagRepository) DetachFromTasks(userID uuid.UUID, tagIDs, taskIDs []uuid.UUID) error {
+	return r.db.Exec(`DELETE FROM task_tags
+		WHERE task_id IN (SELECT id FROM tasks WHERE id IN ? AND user_id = ?)
+		AND tag_id IN ?`, taskIDs, userID, tagIDs).Error
+}
+
+func (r *tagRepository) AttachToNotes(userID uuid.UUID, tagIDs, noteIDs []uuid.UUID) error {
+	return r.db.Exec(`INSERT INTO note_tags (note_id, tag_id)
+		SELECT notes.id, tags.id FROM notes CROSS JOIN tags
+		WHERE notes.id IN ? AND tags.id IN ? AND notes.user_id = ? AND tags.user_id = ?
+		ON CONFLICT DO NOTHING`, noteIDs, tagIDs, userID, userID).Error
+}
+
+func (r *tagRepository) DetachFromNotes(userID uuid.UUID, tagIDs, noteIDs []uuid.UUID) error {
+	return r.db.Exec(`DELETE FROM note_tags
+		WHERE note_id IN (SELECT id FROM notes WHERE id IN ? AND user_id = ?)
+		AND tag_id IN ?`, noteIDs, userID, tagIDs).Error
+}
+
+func (r *tagRepository) Transaction(fn func(repo TagRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&tagRepository{db: tx})
+	})
+}
+
+// applyTagFilter ограничивает запрос объектами с нужными тегами.
+// joinTable/column - таблица связей (task_tags/task_id или note_tags/note_id)
+func applyTagFilter(query *gorm.DB, joinTable, column string, userID uuid.UUID, filter entities.TagFilter) *gorm.DB {
+	if len(filter.Names) == 0 {
+		return query
+	}
+
+	subQuery := fmt.Sprintf(`SELECT jt.%[2]s FROM %[1]s jt
+		JOIN tags ON tags.id = jt.tag_id
+		WHERE tags.user_id = ? AND tags.name IN ?`, joinTable, column)
+
+	if filter.Mode == entities.TagMatchAll {
+		subQuery += " GROUP BY jt." + column + " HAVING COUNT(DISTINCT tags.id) = ?"
+		return query.Where("id IN ("+subQuery+")", userID, filter.Names, len(filter.Names))
+	}
+
+	return query.Where("id IN ("+subQuery+")", userID, filter.Names)
+}
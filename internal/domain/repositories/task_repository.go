@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"errors"
+	"rest-api-notes/internal/domain/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// taskSortFields - белый список сортировок для списка задач.
+// Порядки по priority и due_at покрыты индексами (user_id, col, created_at, id):
+// idx_tasks_user_priority_keyset читается в обе стороны, для due_at NULLS LAST
+// в обоих направлениях нужны два индекса - idx_tasks_user_due_keyset и idx_tasks_user_due_desc_keyset
+var taskSortFields = SortFields{
+	"created_at":  {Column: "created_at"},
+	"-created_at": {Column: "created_at", Desc: true},
+	"priority":    {Column: "priority", Type: SortValueInt},
+	"-priority":   {Column: "priority", Desc: true, Type: SortValueInt},
+	"due_at":      {Column: "due_at", Nullable: true},
+	"-due_at":     {Column: "due_at", Desc: true, Nullable: true},
+}
+
+func taskCursorKey(task *entities.Task, column string) (interface{}, time.Time, uuid.UUID) {
+	var value interface{}
+	switch column {
+	case "priority":
+		value = int64(task.Priority)
+	case "due_at":
+		if task.DueAt != nil {
+			value = *task.DueAt
+		}
+	}
+	return value, task.CreatedAt, task.ID
+}
+
+type taskRepository struct {
+	db        *gorm.DB
+	paginator *Paginator
+}
+
+type TaskRepository interface {
+	Create(task *entities.Task) error
+	GetByID(taskID, userID uuid.UUID) (*entities.Task, error)
+	List(userID uuid.UUID, filter *entities.TaskListFilter, page entities.PageRequest) (*entities.Page[entities.Task], error)
+	Update(taskID, userID uuid.UUID, updates map[string]interface{}) error
+	// UpdateStatus применяет updates, только если задача все еще в статусе from
+	UpdateStatus(taskID, userID uuid.UUID, from entities.TaskStatus, updates map[string]interface{}) error
+	Delete(taskID, userID uuid.UUID) error
+
+	// SubTasks принадлежат задаче, своего владельца у них нет,
+	// поэтому проверка доступа идет через родительскую задачу
+	CreateSubTask(subTask *entities.SubTask) error
+	GetSubTask(subTaskID, taskID uuid.UUID) (*entities.SubTask, error)
+	ListSubTasks(taskID uuid.UUID) ([]entities.SubTask, error)
+	UpdateSubTask(subTaskID, taskID uuid.UUID, updates map[string]interface{}) error
+	DeleteSubTask(subTaskID, taskID uuid.UUID) error
+	DeleteSubTasksByTaskID(taskID uuid.UUID) error
+	DeleteTagLinks(taskID uuid.UUID) error
+	CompleteSubTasks(taskID uuid.UUID, completedAt time.Time) error
+
+	Transaction(fn func(repo TaskRepository) error) error
+}
+
+func NewTaskRepository(db *gorm.DB, paginator *Paginator) TaskRepository {
+	return &taskRepository{db: db, paginator: paginator}
+}
+
+func (r *taskRepository) Create(task *entities.Task) error {
+	return r.db.Create(task).Error
+}
+
+func (r *taskRepository) GetByID(taskID, userID uuid.UUID) (*entities.Task, error) {
+	var task entities.Task
+	err := r.db.
+		Preload("SubTasks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Tags").
+		Where("id = ? AND user_id = ?", taskID, userID).
+		First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+func (r *taskRepository) List(userID uuid.UUID, filter *entities.TaskListFilter, page entities.PageRequest) (*entities.Page[entities.Task], error) {
+	query := r.db.Where("user_id = ?", userID)
+	if filter.DueBefore != nil {
+		query = query.Where("due_at < ?", *filter.DueBefore)
+	}
+	if filter.DueAfter != nil {
+		query = query.Where("due_at > ?", *filter.DueAfter)
+	}
+	if filter.Overdue {
+		query = query.Where("due_at < ? AND status NOT IN ?", time.Now(),
+			[]entities.TaskStatus{entities.TaskStatusDone, entities.TaskStatusCancelled})
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.Where("priority IN ?", filter.Priorities)
+	}
+	query = applyTagFilter(query, "task_tags", "task_id", userID, filter.Tags)
+
+	return Paginate(r.paginator, query.Preload("Tags"), page, taskSortFields, "-created_at", taskCursorKey)
+}
+
+func (r *taskRepository) Update(taskID, userID uuid.UUID, updates map[string]interface{}) error {
+	result := r.db.Model(&entities.Task{}).Where("id = ? AND user_id = ?", taskID, userID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrTaskNotFound
+	}
+	return nil
+}
+
+// Условие на текущий статус делает переход атомарным: из двух параллельных
+// переходов из одного статуса пройдет только первый
+func (r *taskRepository) UpdateStatus(taskID, userID uuid.UUID, from entities.TaskStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&entities.Task{}).
+		Where("id = ? AND user_id = ? AND status = ?", taskID, userID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidStatusTransition
+	}
+	return nil
+}
+
+func (r *taskRepository) Delete(taskID, userID uuid.UUID) error {
+	result := r.db.Where("id = ? AND user_id = ?", taskID, userID).Delete(&entities.Task{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrTaskNotFound
+	}
+	return nil
+}
+
+func (r *taskRepository) CreateSubTask(subTask *entities.SubTask) error {
+	return r.db.Create(subTask).Error
+}
+
+func (r *taskRepository) GetSubTask(subTaskID, taskID uuid.UUID) (*entities.SubTask, error) {
+	var subTask entities.SubTask
+	if err := r.db.Where("id = ? AND task_id = ?", subTaskID, taskID).First(&subTask).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrSubTaskNotFound
+		}
+		return nil, err
+	}
+
+	return &subTask, nil
+}
+
+func (r *taskRepository) ListSubTasks(taskID uuid.UUID) ([]entities.SubTask, error) {
+	subTasks := []entities.SubTask{}
+	if err := r.db.Where("task_id = ?", taskID).Order("created_at ASC").Find(&subTasks).Error; err != nil {
+		return nil, err
+	}
+
+	return subTasks, nil
+}
+
+func (r *taskRepository) UpdateSubTask(subTaskID, taskID uuid.UUID, updates map[string]interface{}) error {
+	result := r.db.Model(&entities.SubTask{}).Where("id = ? AND task_id = ?", subTaskID, taskID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrSubTaskNotFound
+	}
+	return nil
+}
+
+func (r *taskRepository) DeleteSubTask(subTaskID, taskID uuid.UUID) error {
+	result := r.db.Where("id = ? AND task_id = ?", subTaskID, taskID).Delete(&entities.SubTask{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrSubTaskNotFound
+	}
+	return nil
+}
+
+func (r *taskRepository) DeleteSubTasksByTaskID(taskID uuid.UUID) error {
+	return r.db.Where("task_id = ?", taskID).Delete(&entities.SubTask{}).Error
+}
+
+func (r *taskRepository) CompleteSubTasks(taskID uuid.UUID, completedAt time.Time) error {
+	return r.db.Model(&entities.SubTask{}).
+		Where("task_id = ? AND completed = ?", taskID, false).
+		Updates(map[string]interface{}{
+			"completed":    true,
+			"completed_at": completedAt,
+		}).Error
+}
+
+func (r *taskRepository) DeleteTagLinks(taskID uuid.UUID) error {
+	return r.db.Exec("DELETE FROM task_tags WHERE task_id = ?", taskID).Error
+}
+
+// Transaction выполняет fn в одной транзакции, repo внутри fn работает через tx
+func (r *taskRepository) Transaction(fn func(repo TaskRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&taskRepository{db: tx, paginator: r.paginator})
+	})
+}
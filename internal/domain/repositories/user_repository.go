@@ -6,9 +6,11 @@ import (
 	"rest-api-notes/internal/domain/entities"
 	"rest-api-notes/internal/infrastructure/auth"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -26,6 +28,24 @@ type UserRepository interface {
 	UpdatePhoneNumber(phoneNumber string, userID uuid.UUID) error
 	ToggleUser2FA(userID uuid.UUID) error
 	DisableUser2FA(userID uuid.UUID) error
+	EnableTOTP(userID uuid.UUID, secret string) error
+	// ListTOTPSecrets - id и totp_secret всех пользователей, у которых секрет задан
+	ListTOTPSecrets() ([]entities.User, error)
+	UpdateTOTPSecret(userID uuid.UUID, secret string) error
+	UpdateTwoFactorMethod(userID uuid.UUID, method entities.TwoFactorMethod) error
+	UpdatePassword(userID uuid.UUID, passwordHash string) error
+	// MarkEmailVerified подтверждает почту, только если у пользователя все еще этот адрес
+	MarkEmailVerified(userID uuid.UUID, email string) error
+	UpdateUsername(userID uuid.UUID, username string) error
+	// UpdateEmail меняет адрес, подтвержденный по ссылке, поэтому сразу отмечает его проверенным
+	UpdateEmail(userID uuid.UUID, email string) error
+	ScheduleDeletion(userID uuid.UUID, requestedAt time.Time) error
+	CancelDeletion(userID uuid.UUID) error
+	// ListDeletionRequestedBefore - пользователи, чей grace период истек
+	ListDeletionRequestedBefore(cutoff time.Time) ([]uuid.UUID, error)
+	// Purge навсегда удаляет пользователя со всеми его данными в одной транзакции.
+	// ErrUserNotFound - удаление успели отменить или пользователя уже нет
+	Purge(userID uuid.UUID, cutoff time.Time) error
 }
 
 func NewUserRepository(db *gorm.DB, ps auth.PasswordService) UserRepository {
@@ -149,3 +169,169 @@ func (r *userRepository) DisableUser2FA(userID uuid.UUID) error {
 	}
 	return nil
 }
+
+// EnableTOTP сохраняет подтвержденный секрет и сразу включает 2FA через приложение
+func (r *userRepository) EnableTOTP(userID uuid.UUID, secret string) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":        secret,
+		"two_factor_method":  entities.TwoFactorMethodTOTP,
+		"two_factor_enabled": true,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ListTOTPSecrets() ([]entities.User, error) {
+	var users []entities.User
+	if err := r.db.Select("id", "totp_secret").Where("totp_secret <> ''").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) UpdateTOTPSecret(userID uuid.UUID, secret string) error {
+	return r.db.Model(&entities.User{}).Where("id = ?", userID).Update("totp_secret", secret).Error
+}
+
+func (r *userRepository) UpdateTwoFactorMethod(userID uuid.UUID, method entities.TwoFactorMethod) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Update("two_factor_method", method)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(userID uuid.UUID, passwordHash string) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Update("password", passwordHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) MarkEmailVerified(userID uuid.UUID, email string) error {
+	result := r.db.Model(&entities.User{}).Where("id = ? AND email = ?", userID, email).Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrInvalidEmailVerificationToken
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateUsername(userID uuid.UUID, username string) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Update("username", username)
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return entities.ErrUsernameAlreadyTaken
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateEmail(userID uuid.UUID, email string) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"email":             email,
+		"email_verified_at": time.Now(),
+	})
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return entities.ErrEmailAlreadyTaken
+		}
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ScheduleDeletion(userID uuid.UUID, requestedAt time.Time) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Update("deletion_requested_at", requestedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) CancelDeletion(userID uuid.UUID) error {
+	result := r.db.Model(&entities.User{}).Where("id = ?", userID).Update("deletion_requested_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	// Строку держит Purge - после его коммита пользователя уже нет
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ListDeletionRequestedBefore(cutoff time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&entities.User{}).
+		Where("deletion_requested_at IS NOT NULL AND deletion_requested_at < ?", cutoff).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *userRepository) Purge(userID uuid.UUID, cutoff time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Блокируем строку и заново проверяем условие: вход между выборкой и удалением
+		// снимает пометку, и такого пользователя удалять уже нельзя
+		expired := "id = ? AND deletion_requested_at IS NOT NULL AND deletion_requested_at < ?"
+		var user entities.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where(expired, userID, cutoff).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return entities.ErrUserNotFound
+			}
+			return err
+		}
+
+		tasks := tx.Model(&entities.Task{}).Select("id").Where("user_id = ?", userID)
+		notes := tx.Model(&entities.Note{}).Select("id").Where("user_id = ?", userID)
+
+		steps := []func() error{
+			func() error { return tx.Exec("DELETE FROM task_tags WHERE task_id IN (?)", tasks).Error },
+			func() error { return tx.Exec("DELETE FROM note_tags WHERE note_id IN (?)", notes).Error },
+			func() error { return tx.Where("task_id IN (?)", tasks).Delete(&entities.SubTask{}).Error },
+			func() error { return tx.Where("user_id = ?", userID).Delete(&entities.Task{}).Error },
+			func() error { return tx.Where("user_id = ?", userID).Delete(&entities.Note{}).Error },
+			func() error { return tx.Where("user_id = ?", userID).Delete(&entities.Tag{}).Error },
+			func() error { return tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error },
+		}
+		for _, step := range steps {
+			if err := step(); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where(expired, userID, cutoff).Delete(&entities.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entities.ErrUserNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/repositories"
+	"rest-api-notes/internal/infrastructure/cache"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ключ блокировки фоновой задачи, общий для всех инстансов
+const accountPurgeLockKey = "account_purge_lock"
+
+type accountDeletionService struct {
+	userRepo    repositories.UserRepository
+	sS          SessionService
+	redisClient cache.RedisClient
+	gracePeriod time.Duration
+}
+
+// AccountDeletionService - удаление аккаунта с grace периодом: сначала аккаунт только
+// помечается, вход в течение периода отменяет удаление, потом фоновая задача стирает все данные
+type AccountDeletionService interface {
+	// Schedule помечает аккаунт и возвращает, когда он будет удален окончательно
+	Schedule(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	// Restore отменяет запрошенное удаление, если оно было
+	Restore(ctx context.Context, user *entities.User) error
+	// IsExpired - grace период истек, аккаунт ждет только фоновую задачу
+	IsExpired(user *entities.User) bool
+	// Run запускает проверку раз в interval, пока ctx не отменен. Из нескольких
+	// инстансов за интервал проверку выполняет только один
+	Run(ctx context.Context, interval time.Duration)
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+func NewAccountDeletionService(userRepo repositories.UserRepository, sS SessionService,
+	redisClient cache.RedisClient, gracePeriod time.Duration) AccountDeletionService {
+	return &accountDeletionService{
+		userRepo:    userRepo,
+		sS:          sS,
+		redisClient: redisClient,
+		gracePeriod: gracePeriod,
+	}
+}
+
+func (s *accountDeletionService) Schedule(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	now := time.Now()
+	if err := s.userRepo.ScheduleDeletion(userID, now); err != nil {
+		return time.Time{}, err
+	}
+
+	log.Printf("SECURITY: account deletion requested user_id=%s", userID)
+	return now.Add(s.gracePeriod), nil
+}
+
+func (s *accountDeletionService) Restore(ctx context.Context, user *entities.User) error {
+	if user.DeletionRequestedAt == nil {
+		return nil
+	}
+
+	if err := s.userRepo.CancelDeletion(user.ID); err != nil {
+		// Фоновая задача успела удалить аккаунт
+		if errors.Is(err, entities.ErrUserNotFound) {
+			return entities.ErrInvalidCredentials
+		}
+		return err
+	}
+
+	log.Printf("SECURITY: account deletion cancelled by login user_id=%s", user.ID)
+	user.DeletionRequestedAt = nil
+	return nil
+}
+
+func (s *accountDeletionService) IsExpired(user *entities.User) bool {
+	return user.DeletionRequestedAt != nil && time.Since(*user.DeletionRequestedAt) > s.gracePeriod
+}
+
+func (s *accountDeletionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, interval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce выполняет проверку, если ни один инстанс еще не занял этот интервал.
+// Блокировку не снимаем, она живет почти весь интервал, и параллельно с этим
+// запуском другой инстанс не начнет. Запас в десятую часть - чтобы следующий
+// тик этого же инстанса не упирался в собственную блокировку
+func (s *accountDeletionService) runOnce(ctx context.Context, interval time.Duration) {
+	acquired, err := s.redisClient.SetNX(ctx, accountPurgeLockKey, 1, interval-interval/10)
+	if err != nil {
+		log.Printf("Account purge failed: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	if purged, err := s.PurgeExpired(ctx); err != nil {
+		log.Printf("Account purge failed: %v", err)
+	} else if purged > 0 {
+		log.Printf("Account purge: %d accounts deleted", purged)
+	}
+}
+
+func (s *accountDeletionService) PurgeExpired(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.gracePeriod)
+	userIDs, err := s.userRepo.ListDeletionRequestedBefore(cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if err := s.purge(ctx, userID, cutoff); err != nil {
+			if errors.Is(err, entities.ErrUserNotFound) {
+				// Пользователь вошел после выборки и отменил удаление
+				continue
+			}
+			// Остальных удаляем дальше, этот попадет в следующий запуск
+			log.Printf("Failed to purge account user_id=%s: %v", userID, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (s *accountDeletionService) purge(ctx context.Context, userID uuid.UUID, cutoff time.Time) error {
+	if err := s.userRepo.Purge(userID, cutoff); err != nil {
+		return err
+	}
+
+	if err := s.deleteUserKeys(ctx, userID); err != nil {
+		return err
+	}
+
+	log.Printf("SECURITY: account purged user_id=%s", userID)
+	return nil
+}
+
+// deleteUserKeys удаляет ключи пользователя по известным префиксам. Одноразовые токены
+// из писем лежат по хэшу, их находим через ключ пользователя. Rate limit и IP счетчики
+// входа привязаны к окну и истекут сами
+func (s *accountDeletionService) deleteUserKeys(ctx context.Context, userID uuid.UUID) error {
+	// Сессии удаляются вместе с семействами refresh токенов
+	if err := s.sS.RevokeUserTokenFamilies(ctx, userID); err != nil {
+		return err
+	}
+
+	patterns := []string{
+		fmt.Sprintf("trusted_device:%s:*", userID),
+		fmt.Sprintf("2fa_code:%s:*", userID),
+		fmt.Sprintf("2fa_attempts:%s:*", userID),
+		fmt.Sprintf("totp_used:%s:*", userID),
+	}
+	for _, pattern := range patterns {
+		if err := s.redisClient.DeleteByPattern(ctx, pattern); err != nil {
+			return err
+		}
+	}
+
+	if err := s.deletePointedToken(ctx, passwordResetUserKey(userID), passwordResetKey); err != nil {
+		return err
+	}
+	if err := s.deletePointedToken(ctx, emailVerificationUserKey(userID), emailVerificationKey); err != nil {
+		return err
+	}
+	if err := s.deleteEmailChangeTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	keys := []string{
+		loginFailuresKey(userID),
+		loginBlockKey(userID),
+		twoFAFailuresKey(userID),
+		twoFALockKey(userID),
+		fmt.Sprintf("totp_pending:%s", userID),
+		passwordResetUserKey(userID),
+		emailVerificationUserKey(userID),
+		emailChangeUserKey(userID),
+	}
+	for _, key := range keys {
+		if err := s.redisClient.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletePointedToken удаляет токен, хэш которого лежит в ключе пользователя userKey
+func (s *accountDeletionService) deletePointedToken(ctx context.Context, userKey string, tokenKey func(string) string) error {
+	var tokenHash string
+	if err := s.redisClient.GetStruct(ctx, userKey, &tokenHash); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.redisClient.Delete(ctx, tokenKey(tokenHash))
+}
+
+// deleteEmailChangeTokens - ссылки подтверждения и отмены смены почты, как в emailChangeService
+func (s *accountDeletionService) deleteEmailChangeTokens(ctx context.Context, userID uuid.UUID) error {
+	var pending entities.PendingEmailChange
+	if err := s.redisClient.GetStruct(ctx, emailChangeUserKey(userID), &pending); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.redisClient.Delete(ctx, emailChangeTokenKey(pending.ConfirmHash)); err != nil {
+		return err
+	}
+	return s.redisClient.Delete(ctx, emailChangeTokenKey(pending.CancelHash))
+}
@@ -2,9 +2,13 @@ package services
 
 import (
 	"context"
+	"errors"
+	"log"
 	"rest-api-notes/internal/domain/entities"
 	"rest-api-notes/internal/domain/repositories"
 	"slices"
+	"strings"
+	"time"
 
 	"rest-api-notes/internal/infrastructure/auth"
 
@@ -17,6 +21,12 @@ type authService struct {
 	uR               repositories.UserRepository
 	sS               SessionService
 	twoFactorService TwoFactorService
+	tdS              TrustedDeviceService
+	lAS              LoginAttemptService
+	prS              PasswordResetService
+	evS              EmailVerificationService
+	ecS              EmailChangeService
+	adS              AccountDeletionService
 }
 
 type AuthService interface {
@@ -25,13 +35,24 @@ type AuthService interface {
 	Logout(ctx context.Context, req *entities.UserLogoutReq) error
 	GetNewTokens(ctx context.Context, req *entities.UserGetNewTokensReq, userAgent, userIp string) (*entities.Session, error)
 	CreateNewSessionAndTokens(ctx context.Context, userId uuid.UUID, userAgent, userIp string) (*entities.Session, error)
-	Verify2FACode(ctx context.Context, code, token, userAgent, userIP string, userID uuid.UUID) (*entities.Session, error)
+	// Если rememberDevice, вторым значением возвращает токен доверенного устройства для cookie
+	Verify2FACode(ctx context.Context, code, token, userAgent, userIP string, userID uuid.UUID, rememberDevice bool) (*entities.Session, string, error)
 	Resend2FACode(ctx context.Context, userID uuid.UUID, userAgent, userIP string) (*string, error)
+	UnlockAccount(ctx context.Context, userID uuid.UUID) error
+	// ForgotPassword не сообщает, существует ли email - ответ всегда одинаковый
+	ForgotPassword(ctx context.Context, req *entities.PasswordForgotReq) error
+	ResetPassword(ctx context.Context, req *entities.PasswordResetReq) error
+	VerifyEmail(ctx context.Context, req *entities.EmailVerifyReq) error
+	ConfirmEmailChange(ctx context.Context, req *entities.EmailChangeTokenReq) error
+	CancelEmailChange(ctx context.Context, req *entities.EmailChangeTokenReq) error
 }
 
-func NewAuthService(jS auth.JWTService, pS auth.PasswordService,
-	uR repositories.UserRepository, rS SessionService, twoFactorService TwoFactorService) AuthService {
-	return &authService{jS: jS, pS: pS, uR: uR, sS: rS, twoFactorService: twoFactorService}
+func NewAuthService(jS auth.JWTService, pS auth.PasswordService, uR repositories.UserRepository,
+	rS SessionService, twoFactorService TwoFactorService, tdS TrustedDeviceService, lAS LoginAttemptService,
+	prS PasswordResetService, evS EmailVerificationService, ecS EmailChangeService,
+	adS AccountDeletionService) AuthService {
+	return &authService{jS: jS, pS: pS, uR: uR, sS: rS, twoFactorService: twoFactorService,
+		tdS: tdS, lAS: lAS, prS: prS, evS: evS, ecS: ecS, adS: adS}
 }
 
 func (s *authService) Login(ctx context.Context,
@@ -39,19 +60,39 @@ func (s *authService) Login(ctx context.Context,
 	//
 	user, err := s.uR.FindUserByEmailOrUsername(req.Identifier)
 	if err != nil {
-		return nil, nil, err
+		if !errors.Is(err, entities.ErrUserNotFound) {
+			return nil, nil, err
+		}
+		// Несуществующий логин тоже неудача: перебор по списку логинов упрется в лимит IP,
+		// а ответ не выдаст, есть ли такой пользователь
+		attempt, err := s.lAS.Reserve(ctx, uuid.Nil, userIp)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, s.lAS.RegisterFailure(ctx, attempt)
 	}
 
-	err = s.pS.ValidatePassword(req.Password)
+	// Политику паролей при входе не проверяем: она для установки пароля, а здесь
+	// отдельная ошибка выдала бы, что аккаунт существует
+	attempt, err := s.lAS.Reserve(ctx, user.ID, userIp)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	err = s.pS.ComparePasswords(user.Password, req.Password)
 	if err != nil {
+		return nil, nil, s.lAS.RegisterFailure(ctx, attempt)
+	}
+
+	if err := s.lAS.RegisterSuccess(ctx, attempt); err != nil {
 		return nil, nil, err
 	}
 
+	// Grace период удаления истек - для клиента аккаунта уже нет
+	if s.adS.IsExpired(user) {
+		return nil, nil, entities.ErrInvalidCredentials
+	}
+
 	userSessions, err := s.sS.GetAllUserSessions(ctx, user.ID)
 	if err != nil {
 		return nil, nil, err
@@ -75,21 +116,23 @@ func (s *authService) Login(ctx context.Context,
 		Role:     entities.RoleType(user.Role.String()),
 	}
 
-	// Проверка на то, включена ли 2FA, если да -> что-то делаем и не отдаем сессию.
-	if user.TwoFactorEnabled {
-		code, err := s.twoFactorService.Generate2FACode()
+	trusted := false
+	if user.TwoFactorEnabled && req.TrustedDeviceToken != "" {
+		trusted, err = s.tdS.IsTrusted(ctx, user.ID, req.TrustedDeviceToken, userAgent)
 		if err != nil {
 			return nil, nil, err
 		}
+	}
+
+	// Проверка на то, включена ли 2FA, если да -> что-то делаем и не отдаем сессию.
+	// С доверенного устройства второй фактор не спрашиваем
+	if user.TwoFactorEnabled && !trusted {
 		token, err := s.jS.Generate2FAToken(user.ID, userAgent, userIp)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		if err := s.sS.Save2FACode(ctx, user.ID, code, "login", token); err != nil {
-			return nil, nil, err
-		}
-		if err = s.twoFactorService.MakeRequestToGateway(ctx, user.PhoneNumber, code); err != nil {
+		if err := s.twoFactorService.IssueCode(ctx, user, entities.TwoFAContextLogin, token); err != nil {
 			return nil, nil, err
 		}
 
@@ -98,6 +141,11 @@ func (s *authService) Login(ctx context.Context,
 		return &entities.UserAuthRes{TwoFactorToken: token}, nil, entities.Err2FARequired
 	}
 
+	// Успешный вход отменяет запрошенное удаление аккаунта
+	if err := s.adS.Restore(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
 	session, err := s.CreateNewSessionAndTokens(ctx, res.ID, userAgent, userIp)
 	if err != nil {
 		return nil, nil, err
@@ -114,7 +162,7 @@ func (s *authService) Register(ctx context.Context,
 		return nil, nil, err
 	}
 
-	err := s.pS.ValidatePassword(req.Password)
+	err := s.validatePassword(req.Password)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -130,6 +178,11 @@ func (s *authService) Register(ctx context.Context,
 		return nil, nil, err
 	}
 
+	// Регистрация не ждет почтовый сервер, письмо всегда можно запросить повторно
+	sendMailDetached(ctx, "email verification", user.ID, func(ctx context.Context) error {
+		return s.evS.SendVerification(ctx, user)
+	})
+
 	res := entities.UserAuthRes{
 		ID:       user.ID,
 		Username: user.Username,
@@ -145,22 +198,44 @@ func (s *authService) Register(ctx context.Context,
 	return &res, session, nil
 }
 
-func (s *authService) Verify2FACode(ctx context.Context, code, token, userAgent, userIP string, userID uuid.UUID) (*entities.Session, error) {
-	data, err := s.sS.Verify2FACode(ctx, userID, code, "login")
+func (s *authService) Verify2FACode(ctx context.Context, code, token, userAgent, userIP string,
+	userID uuid.UUID, rememberDevice bool) (*entities.Session, string, error) {
+	user, err := s.uR.GetUserById(userID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	data, err := s.twoFactorService.VerifyUserCode(ctx, user, code, entities.TwoFAContextLogin)
+	if err != nil {
+		return nil, "", err
 	}
 
 	if data.Token != token {
-		return nil, entities.Err2FASessionAndTokenMismatch
+		return nil, "", entities.Err2FASessionAndTokenMismatch
+	}
+
+	if s.adS.IsExpired(user) {
+		return nil, "", entities.ErrInvalidCredentials
+	}
+	if err := s.adS.Restore(ctx, user); err != nil {
+		return nil, "", err
 	}
 
 	session, err := s.CreateNewSessionAndTokens(ctx, userID, userAgent, userIP)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return session, nil
+	if !rememberDevice {
+		return session, "", nil
+	}
+
+	deviceToken, err := s.tdS.Trust(ctx, userID, userAgent, userIP)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, deviceToken, nil
 }
 
 func (s *authService) Resend2FACode(ctx context.Context, userID uuid.UUID, userAgent, userIP string) (*string, error) {
@@ -170,19 +245,12 @@ func (s *authService) Resend2FACode(ctx context.Context, userID uuid.UUID, userA
 	}
 
 	if user.TwoFactorEnabled {
-		code, err := s.twoFactorService.Generate2FACode()
-		if err != nil {
-			return nil, err
-		}
 		token, err := s.jS.Generate2FAToken(user.ID, userAgent, userIP)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := s.sS.Save2FACode(ctx, user.ID, code, "login", token); err != nil {
-			return nil, err
-		}
-		if err = s.twoFactorService.MakeRequestToGateway(ctx, user.PhoneNumber, code); err != nil {
+		if err := s.twoFactorService.IssueCode(ctx, user, entities.TwoFAContextLogin, token); err != nil {
 			return nil, err
 		}
 
@@ -191,8 +259,135 @@ func (s *authService) Resend2FACode(ctx context.Context, userID uuid.UUID, userA
 	return nil, entities.Err2FADisabled
 }
 
+func (s *authService) UnlockAccount(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.uR.GetUserById(userID); err != nil {
+		return err
+	}
+
+	return s.lAS.Unlock(ctx, userID)
+}
+
+func (s *authService) ForgotPassword(ctx context.Context, req *entities.PasswordForgotReq) error {
+	user, err := s.uR.GetUserByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		return nil
+	}
+
+	// Письмо уходит в фоне, чтобы время ответа не выдавало, есть ли такой email
+	sendMailDetached(ctx, "password reset link", user.ID, func(ctx context.Context) error {
+		return s.prS.SendResetLink(ctx, user)
+	})
+
+	return nil
+}
+
+// ResetPassword меняет пароль по токену из письма и разлогинивает все устройства:
+// если сброс делает не владелец, тот хотя бы заметит, что его выкинуло
+func (s *authService) ResetPassword(ctx context.Context, req *entities.PasswordResetReq) error {
+	// Проверяем пароль до токена, чтобы слабый пароль не сжигал ссылку
+	if err := s.validatePassword(req.Password); err != nil {
+		return err
+	}
+
+	userID, err := s.prS.ConsumeToken(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+
+	password, err := s.pS.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.uR.UpdatePassword(userID, password); err != nil {
+		return err
+	}
+
+	log.Printf("SECURITY: password reset via email link user_id=%s", userID)
+
+	if err := s.sS.DeleteAllUserSessions(ctx, userID, ""); err != nil {
+		return err
+	}
+	if err := s.tdS.RevokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	// Владелец почты подтвердил себя, блокировку после перебора снимаем
+	return s.lAS.Reset(ctx, userID)
+}
+
+func (s *authService) VerifyEmail(ctx context.Context, req *entities.EmailVerifyReq) error {
+	data, err := s.evS.ConsumeToken(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+
+	return s.uR.MarkEmailVerified(data.UserID, data.Email)
+}
+
+func (s *authService) ConfirmEmailChange(ctx context.Context, req *entities.EmailChangeTokenReq) error {
+	pending, err := s.ecS.Confirm(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.uR.GetUserById(pending.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Почту успели сменить другим путем - эта смена уже неактуальна
+	if user.Email != pending.OldEmail {
+		return entities.ErrInvalidEmailChangeToken
+	}
+
+	// Адрес мог занять кто-то другой, пока письмо шло
+	if _, err := s.uR.GetUserByEmail(pending.NewEmail); err == nil {
+		return entities.ErrEmailAlreadyTaken
+	} else if !errors.Is(err, entities.ErrUserNotFound) {
+		return err
+	}
+
+	if err := s.uR.UpdateEmail(user.ID, pending.NewEmail); err != nil {
+		return err
+	}
+
+	log.Printf("SECURITY: email changed user_id=%s", user.ID)
+	return nil
+}
+
+func (s *authService) CancelEmailChange(ctx context.Context, req *entities.EmailChangeTokenReq) error {
+	return s.ecS.Cancel(ctx, req.Token)
+}
+
+// sendMailDetached отправляет письмо в фоне, отдельно от отмены запроса
+func sendMailDetached(ctx context.Context, what string, userID uuid.UUID, send func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := send(ctx); err != nil {
+			log.Printf("Failed to send %s user_id=%s: %v", what, userID, err)
+		}
+	}()
+}
+
+func (s *authService) validatePassword(password string) error {
+	return validatePasswordPolicy(s.pS, password)
+}
+
+// validatePasswordPolicy переводит ошибку политики паролей в доменную, чтобы клиент получил WEAK_PASSWORD
+func validatePasswordPolicy(pS auth.PasswordService, password string) error {
+	if err := pS.ValidatePassword(password); err != nil {
+		if errors.Is(err, auth.ErrWeakPassword) {
+			return entities.ErrWeakPassword
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *authService) Logout(ctx context.Context, req *entities.UserLogoutReq) error {
-	refreshClaim, err := s.jS.ValidateToken(req.RefreshToken, "refresh")
+	refreshClaim, err := s.jS.ValidateToken(req.RefreshToken, auth.JWTTypeRefresh)
 	if err != nil {
 		return err
 	}
@@ -207,6 +402,15 @@ func (s *authService) Logout(ctx context.Context, req *entities.UserLogoutReq) e
 	}
 
 	if isSessionValid {
+		session, err := s.sS.GetSession(ctx, refreshClaim.UserID, refreshClaim.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if session != nil && session.FamilyID != "" {
+			return s.sS.RevokeTokenFamily(ctx, session.FamilyID)
+		}
+
 		err = s.sS.DeleteSession(ctx, refreshClaim.UserID, refreshClaim.SessionID)
 		if err != nil {
 			return err
@@ -220,7 +424,7 @@ func (s *authService) GetNewTokens(ctx context.Context,
 	req *entities.UserGetNewTokensReq, userAgent, userIp string) (*entities.Session, error) {
 	// Получаем refresh токен -> Проверяем его валидность -> Проверяем сессию ->
 	// -> Сверяем refresh токен с сессией и UserAgent && IP -> Перевыпускаем новые токены -> Обновляем сессию
-	refreshClaim, err := s.jS.ValidateToken(req.RefreshToken, "refresh")
+	refreshClaim, err := s.jS.ValidateToken(req.RefreshToken, auth.JWTTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
@@ -235,6 +439,16 @@ func (s *authService) GetNewTokens(ctx context.Context,
 	}
 
 	if !isValid {
+		// Токен уже был обменян раньше -> кто-то повторяет украденный токен.
+		// Отзываем все семейство, обоим (и вору, и владельцу) придется логиниться заново
+		rotated, err := s.sS.GetRotatedRefreshToken(ctx, req.RefreshToken)
+		if err != nil {
+			return nil, err
+		}
+		if rotated != nil {
+			return nil, s.handleRefreshTokenReuse(ctx, rotated.UserID, rotated.FamilyID, rotated.SessionID, userAgent, userIp)
+		}
+
 		return nil, entities.ErrSessionExpired
 	}
 
@@ -251,11 +465,36 @@ func (s *authService) GetNewTokens(ctx context.Context,
 		return nil, entities.ErrSessionBelongsToAnotherDevice
 	}
 
+	// Сессии до появления семейств FamilyID не имеют, начинаем для них новое
+	familyID := prevSession.FamilyID
+	if familyID == "" {
+		familyID = uuid.New().String()
+		prevSession.FamilyID = familyID
+	}
+
+	// Проверки выше не атомарны: два запроса с одним токеном могут пройти их оба.
+	// Обменять токен может только тот, кто первым его занял, второй - это повтор
+	claimed, err := s.sS.ClaimRefreshToken(ctx, req.RefreshToken, prevSession)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		// Семью берем из метки победителя: у старых сессий без FamilyID она сгенерирована им
+		rotated, err := s.sS.GetRotatedRefreshToken(ctx, req.RefreshToken)
+		if err != nil {
+			return nil, err
+		}
+		if rotated != nil {
+			familyID = rotated.FamilyID
+		}
+		return nil, s.handleRefreshTokenReuse(ctx, refreshClaim.UserID, familyID, refreshClaim.SessionID, userAgent, userIp)
+	}
+
 	if err = s.sS.DeleteSession(ctx, refreshClaim.UserID, refreshClaim.SessionID); err != nil {
 		return nil, err
 	}
 
-	newSession, err := s.CreateNewSessionAndTokens(ctx, refreshClaim.UserID, userAgent, userIp)
+	newSession, err := s.createSessionInFamily(ctx, refreshClaim.UserID, familyID, userAgent, userIp)
 	if err != nil {
 		return nil, err
 	}
@@ -263,7 +502,21 @@ func (s *authService) GetNewTokens(ctx context.Context,
 	return newSession, nil
 }
 
+func (s *authService) handleRefreshTokenReuse(ctx context.Context, userID uuid.UUID, familyID, sessionID, userAgent, userIp string) error {
+	log.Printf("SECURITY: refresh token reuse detected user_id=%s family_id=%s session_id=%s ip=%s user_agent=%q",
+		userID, familyID, sessionID, userIp, userAgent)
+	if err := s.sS.RevokeTokenFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return entities.ErrRefreshTokenReused
+}
+
+// CreateNewSessionAndTokens создает сессию с новым семейством refresh токенов (логин, регистрация, 2FA)
 func (s *authService) CreateNewSessionAndTokens(ctx context.Context, userId uuid.UUID, userAgent, userIP string) (*entities.Session, error) {
+	return s.createSessionInFamily(ctx, userId, uuid.New().String(), userAgent, userIP)
+}
+
+func (s *authService) createSessionInFamily(ctx context.Context, userId uuid.UUID, familyID, userAgent, userIP string) (*entities.Session, error) {
 	sessionID := uuid.New().String()
 
 	accToken, err := s.jS.GenerateToken(userId, sessionID, "access")
@@ -276,10 +529,41 @@ func (s *authService) CreateNewSessionAndTokens(ctx context.Context, userId uuid
 		return nil, entities.ErrFailedToCreateRefreshToken
 	}
 
-	session, err := s.sS.CreateSession(ctx, userId, sessionID, accToken, refToken, userAgent, userIP)
+	session, err := s.sS.CreateSession(ctx, userId, sessionID, familyID, accToken, refToken, userAgent, userIP)
+	if err != nil {
+		return nil, err
+	}
+
+	family, err := s.sS.GetTokenFamily(ctx, familyID)
 	if err != nil {
 		return nil, err
 	}
+	if family == nil {
+		family = &entities.TokenFamily{
+			FamilyID:  familyID,
+			UserID:    userId,
+			CreatedAt: time.Now().Unix(),
+		}
+	}
+	family.CurrentSessionID = sessionID
+	family.SessionIDs = append(family.SessionIDs, sessionID)
+
+	if err := s.sS.SaveTokenFamily(ctx, family); err != nil {
+		return nil, err
+	}
+
+	// Семейство могли отозвать, пока шла ротация. RevokeTokenFamily ставит метку
+	// до чтения семьи, так что либо он увидит новую сессию, либо мы увидим метку
+	revoked, err := s.sS.IsTokenFamilyRevoked(ctx, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		if err := s.sS.DeleteSession(ctx, userId, sessionID); err != nil {
+			return nil, err
+		}
+		return nil, entities.ErrRefreshTokenReused
+	}
 
 	return session, nil
 }
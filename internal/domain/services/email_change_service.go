@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/cache"
+	"rest-api-notes/internal/infrastructure/notification"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type emailChangeService struct {
+	redisClient cache.RedisClient
+	mailer      notification.Mailer
+	confirmURL  string
+	cancelURL   string
+	ttl         time.Duration
+}
+
+// EmailChangeService - смена почты в два шага: ссылка подтверждения уходит на новый адрес,
+// ссылка отмены на старый. Адрес в БД меняется только после подтверждения
+type EmailChangeService interface {
+	// Start заменяет предыдущий незавершенный запрос и отправляет оба письма
+	Start(ctx context.Context, user *entities.User, newEmail string) error
+	// Confirm по токену из письма на новый адрес возвращает подтвержденную смену и удаляет ее
+	Confirm(ctx context.Context, token string) (*entities.PendingEmailChange, error)
+	Cancel(ctx context.Context, token string) error
+}
+
+func NewEmailChangeService(redisClient cache.RedisClient, mailer notification.Mailer,
+	confirmURL, cancelURL string, ttl time.Duration) EmailChangeService {
+	return &emailChangeService{
+		redisClient: redisClient,
+		mailer:      mailer,
+		confirmURL:  confirmURL,
+		cancelURL:   cancelURL,
+		ttl:         ttl,
+	}
+}
+
+func (s *emailChangeService) Start(ctx context.Context, user *entities.User, newEmail string) error {
+	confirmToken, confirmHash, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+	cancelToken, cancelHash, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.deletePending(ctx, user.ID); err != nil {
+		return err
+	}
+
+	pending := entities.PendingEmailChange{
+		UserID:      user.ID,
+		OldEmail:    user.Email,
+		NewEmail:    newEmail,
+		ConfirmHash: confirmHash,
+		CancelHash:  cancelHash,
+		ExpiresAt:   time.Now().Add(s.ttl).Unix(),
+	}
+	if err := s.redisClient.SetStruct(ctx, emailChangeUserKey(user.ID), &pending, s.ttl); err != nil {
+		return err
+	}
+	if err := s.redisClient.SetStruct(ctx, emailChangeTokenKey(confirmHash), user.ID, s.ttl); err != nil {
+		return err
+	}
+	if err := s.redisClient.SetStruct(ctx, emailChangeTokenKey(cancelHash), user.ID, s.ttl); err != nil {
+		return err
+	}
+
+	confirmLink := s.confirmURL + "?token=" + url.QueryEscape(confirmToken)
+	confirmBody := fmt.Sprintf("Hi, %s!\r\n\r\n"+
+		"To use this address for your account, open this link within %d hours:\r\n%s\r\n\r\n"+
+		"If you did not request this, ignore this email.",
+		user.Username, int(s.ttl.Hours()), confirmLink)
+	if err := s.mailer.Send(ctx, newEmail, "Confirm your new email address", confirmBody); err != nil {
+		return err
+	}
+
+	cancelLink := s.cancelURL + "?token=" + url.QueryEscape(cancelToken)
+	cancelBody := fmt.Sprintf("Hi, %s!\r\n\r\n"+
+		"Someone requested to change the email address of your account to %s.\r\n"+
+		"If it was not you, cancel the change with this link and change your password:\r\n%s",
+		user.Username, newEmail, cancelLink)
+	return s.mailer.Send(ctx, user.Email, "Email address change requested", cancelBody)
+}
+
+func (s *emailChangeService) Confirm(ctx context.Context, token string) (*entities.PendingEmailChange, error) {
+	tokenHash := hashOneTimeToken(token)
+	pending, err := s.consume(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending.ConfirmHash != tokenHash {
+		return nil, entities.ErrInvalidEmailChangeToken
+	}
+
+	if err := s.deletePending(ctx, pending.UserID); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+func (s *emailChangeService) Cancel(ctx context.Context, token string) error {
+	tokenHash := hashOneTimeToken(token)
+	pending, err := s.consume(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	if pending.CancelHash != tokenHash {
+		return entities.ErrInvalidEmailChangeToken
+	}
+
+	return s.deletePending(ctx, pending.UserID)
+}
+
+// consume сжигает токен и возвращает смену, к которой он относится
+func (s *emailChangeService) consume(ctx context.Context, tokenHash string) (*entities.PendingEmailChange, error) {
+	var userID uuid.UUID
+	if err := s.redisClient.GetDelStruct(ctx, emailChangeTokenKey(tokenHash), &userID); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, entities.ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	var pending entities.PendingEmailChange
+	if err := s.redisClient.GetStruct(ctx, emailChangeUserKey(userID), &pending); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, entities.ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	if time.Now().Unix() > pending.ExpiresAt {
+		return nil, entities.ErrInvalidEmailChangeToken
+	}
+
+	return &pending, nil
+}
+
+// deletePending удаляет запрос вместе с обеими ссылками
+func (s *emailChangeService) deletePending(ctx context.Context, userID uuid.UUID) error {
+	var pending entities.PendingEmailChange
+	if err := s.redisClient.GetStruct(ctx, emailChangeUserKey(userID), &pending); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.redisClient.Delete(ctx, emailChangeTokenKey(pending.ConfirmHash)); err != nil {
+		return err
+	}
+	if err := s.redisClient.Delete(ctx, emailChangeTokenKey(pending.CancelHash)); err != nil {
+		return err
+	}
+	return s.redisClient.Delete(ctx, emailChangeUserKey(userID))
+}
+
+func emailChangeTokenKey(tokenHash string) string {
+	return fmt.Sprintf("email_change:%s", tokenHash)
+}
+
+func emailChangeUserKey(userID uuid.UUID) string {
+	return fmt.Sprintf("email_change_user:%s", userID)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/cache"
+	"rest-api-notes/internal/infrastructure/notification"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type emailVerificationService struct {
+	redisClient cache.RedisClient
+	mailer      notification.Mailer
+	verifyURL   string
+	ttl         time.Duration
+	requiredFor []string
+}
+
+// EmailVerificationService - подтверждение почты одноразовой ссылкой и политика,
+// какие действия недоступны до подтверждения
+type EmailVerificationService interface {
+	// SendVerification выпускает новый токен (старый перестает работать) и отправляет ссылку на user.Email
+	SendVerification(ctx context.Context, user *entities.User) error
+	// ConsumeToken проверяет токен и сразу удаляет его, повторно он уже не сработает
+	ConsumeToken(ctx context.Context, token string) (*entities.EmailVerificationToken, error)
+	// RequireVerified возвращает ErrEmailNotVerified, если действие закрыто политикой для неподтвержденной почты
+	RequireVerified(user *entities.User, action string) error
+}
+
+func NewEmailVerificationService(redisClient cache.RedisClient, mailer notification.Mailer,
+	verifyURL string, ttl time.Duration, requiredFor []string) EmailVerificationService {
+	return &emailVerificationService{
+		redisClient: redisClient,
+		mailer:      mailer,
+		verifyURL:   verifyURL,
+		ttl:         ttl,
+		requiredFor: requiredFor,
+	}
+}
+
+func (s *emailVerificationService) SendVerification(ctx context.Context, user *entities.User) error {
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	var prevHash string
+	if err := s.redisClient.GetStruct(ctx, emailVerificationUserKey(user.ID), &prevHash); err == nil {
+		if err := s.redisClient.Delete(ctx, emailVerificationKey(prevHash)); err != nil {
+			return err
+		}
+	}
+
+	data := entities.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	}
+	if err := s.redisClient.SetStruct(ctx, emailVerificationKey(tokenHash), &data, s.ttl); err != nil {
+		return err
+	}
+	if err := s.redisClient.SetStruct(ctx, emailVerificationUserKey(user.ID), tokenHash, s.ttl); err != nil {
+		return err
+	}
+
+	link := s.verifyURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Welcome, %s!\r\n\r\n"+
+		"Please confirm your email address by opening this link within %d hours:\r\n%s\r\n\r\n"+
+		"If you did not create an account, ignore this email.",
+		user.Username, int(s.ttl.Hours()), link)
+
+	return s.mailer.Send(ctx, user.Email, "Confirm your email address", body)
+}
+
+func (s *emailVerificationService) ConsumeToken(ctx context.Context, token string) (*entities.EmailVerificationToken, error) {
+	var data entities.EmailVerificationToken
+	if err := s.redisClient.GetDelStruct(ctx, emailVerificationKey(hashOneTimeToken(token)), &data); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, entities.ErrInvalidEmailVerificationToken
+		}
+		return nil, err
+	}
+
+	if time.Now().Unix() > data.ExpiresAt {
+		return nil, entities.ErrInvalidEmailVerificationToken
+	}
+
+	if err := s.redisClient.Delete(ctx, emailVerificationUserKey(data.UserID)); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+func (s *emailVerificationService) RequireVerified(user *entities.User, action string) error {
+	if user.IsEmailVerified() || !slices.Contains(s.requiredFor, action) {
+		return nil
+	}
+	return entities.ErrEmailNotVerified
+}
+
+func emailVerificationKey(tokenHash string) string {
+	return fmt.Sprintf("email_verification:%s", tokenHash)
+}
+
+func emailVerificationUserKey(userID uuid.UUID) string {
+	return fmt.Sprintf("email_verification_user:%s", userID)
+}
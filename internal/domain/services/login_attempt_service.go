@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/cache"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type loginAttemptService struct {
+	redisClient   cache.RedisClient
+	maxAttempts   int
+	lockout       time.Duration
+	ipMaxAttempts int
+	ipWindow      time.Duration
+}
+
+// LoginAttemptService - защита входа по паролю от перебора: счетчик неудач на аккаунт
+// с прогрессивной задержкой и блокировкой, плюс общий лимит неудач с одного IP
+// по всем аккаунтам против credential stuffing
+type LoginAttemptService interface {
+	// Reserve занимает попытку до проверки пароля (INCR), поэтому параллельные
+	// запросы не проскочат лимит. userID == uuid.Nil - пользователь не найден, считаем только IP
+	Reserve(ctx context.Context, userID uuid.UUID, ip string) (*LoginReservation, error)
+	// RegisterFailure - пароль не подошел, возвращает ошибку для ответа клиенту
+	RegisterFailure(ctx context.Context, r *LoginReservation) error
+	// RegisterSuccess возвращает попытку IP и сбрасывает счетчик аккаунта
+	RegisterSuccess(ctx context.Context, r *LoginReservation) error
+	Reset(ctx context.Context, userID uuid.UUID) error
+	// Unlock - ручная разблокировка аккаунта администратором
+	Unlock(ctx context.Context, userID uuid.UUID) error
+}
+
+// LoginReservation - занятая попытка входа. Ключ IP счетчика запоминаем,
+// потому что к моменту ответа окно могло смениться
+type LoginReservation struct {
+	userID   uuid.UUID
+	ip       string
+	ipKey    string
+	failures int64
+}
+
+func NewLoginAttemptService(redisClient cache.RedisClient, maxAttempts int, lockout time.Duration,
+	ipMaxAttempts int, ipWindow time.Duration) LoginAttemptService {
+	return &loginAttemptService{
+		redisClient:   redisClient,
+		maxAttempts:   maxAttempts,
+		lockout:       lockout,
+		ipMaxAttempts: ipMaxAttempts,
+		ipWindow:      ipWindow,
+	}
+}
+
+func (s *loginAttemptService) Reserve(ctx context.Context, userID uuid.UUID, ip string) (*LoginReservation, error) {
+	windowStart := time.Now().Truncate(s.ipWindow)
+	r := &LoginReservation{userID: userID, ip: ip, ipKey: ipFailuresKey(ip, windowStart)}
+
+	ipAttempts, err := s.redisClient.IncrExpire(ctx, r.ipKey, s.ipWindow)
+	if err != nil {
+		return nil, err
+	}
+	if ipAttempts > int64(s.ipMaxAttempts) {
+		if ipAttempts == int64(s.ipMaxAttempts)+1 {
+			log.Printf("SECURITY: login failures limit reached for ip=%s", ip)
+		}
+		return nil, entities.NewLoginThrottledError(windowStart.Add(s.ipWindow))
+	}
+
+	if userID == uuid.Nil {
+		return r, nil
+	}
+
+	// Отказ без проверки пароля попыткой IP не считаем
+	if err := s.checkBlock(ctx, userID); err != nil {
+		return nil, errors.Join(err, s.releaseIP(ctx, r))
+	}
+
+	key := loginFailuresKey(userID)
+	// Счетчик забывается через время блокировки после первой неудачной попытки
+	failures, err := s.redisClient.IncrExpire(ctx, key, s.lockout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Попытки сверх лимита заняли параллельные запросы, блокировку поставит RegisterFailure
+	// одного из них, а этот даже не доходит до сравнения пароля
+	if failures > int64(s.maxAttempts) {
+		if err := s.releaseIP(ctx, r); err != nil {
+			return nil, err
+		}
+		return nil, entities.NewAccountLockedError(time.Now().Add(s.lockout))
+	}
+
+	r.failures = failures
+	return r, nil
+}
+
+func (s *loginAttemptService) checkBlock(ctx context.Context, userID uuid.UUID) error {
+	var block entities.LoginBlock
+	if err := s.redisClient.GetStruct(ctx, loginBlockKey(userID), &block); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	until := time.UnixMilli(block.UntilMs)
+	if !time.Now().Before(until) {
+		return nil
+	}
+	if block.Locked {
+		return entities.NewAccountLockedError(until)
+	}
+	return entities.NewLoginThrottledError(until)
+}
+
+func (s *loginAttemptService) RegisterFailure(ctx context.Context, r *LoginReservation) error {
+	if r.userID == uuid.Nil {
+		return entities.ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	delay, locked := loginBackoff(r.failures, s.maxAttempts, s.lockout)
+	if locked {
+		until := now.Add(delay)
+		log.Printf("SECURITY: account locked after %d failed login attempts user_id=%s ip=%s until=%s",
+			r.failures, r.userID, r.ip, until.UTC().Format(time.RFC3339))
+
+		block := entities.LoginBlock{UntilMs: until.UnixMilli(), Locked: true}
+		if err := s.redisClient.SetStruct(ctx, loginBlockKey(r.userID), &block, delay); err != nil {
+			return err
+		}
+		if err := s.redisClient.Delete(ctx, loginFailuresKey(r.userID)); err != nil {
+			return err
+		}
+		return entities.NewAccountLockedError(until)
+	}
+
+	if delay > 0 {
+		block := entities.LoginBlock{UntilMs: now.Add(delay).UnixMilli()}
+		if err := s.redisClient.SetStruct(ctx, loginBlockKey(r.userID), &block, delay); err != nil {
+			return err
+		}
+	}
+
+	return entities.ErrInvalidCredentials
+}
+
+func (s *loginAttemptService) RegisterSuccess(ctx context.Context, r *LoginReservation) error {
+	if err := s.releaseIP(ctx, r); err != nil {
+		return err
+	}
+	return s.Reset(ctx, r.userID)
+}
+
+// releaseIP возвращает попытку в IP счетчик. Если окно уже истекло, DECR создал бы
+// ключ без TTL со значением -1, такой ключ сразу удаляем
+func (s *loginAttemptService) releaseIP(ctx context.Context, r *LoginReservation) error {
+	left, err := s.redisClient.Decr(ctx, r.ipKey)
+	if err != nil {
+		return err
+	}
+	if left < 0 {
+		return s.redisClient.Delete(ctx, r.ipKey)
+	}
+	return nil
+}
+
+func (s *loginAttemptService) Reset(ctx context.Context, userID uuid.UUID) error {
+	if err := s.redisClient.Delete(ctx, loginFailuresKey(userID)); err != nil {
+		return err
+	}
+	return s.redisClient.Delete(ctx, loginBlockKey(userID))
+}
+
+func (s *loginAttemptService) Unlock(ctx context.Context, userID uuid.UUID) error {
+	log.Printf("SECURITY: account unlocked manually user_id=%s", userID)
+	return s.Reset(ctx, userID)
+}
+
+// loginBackoff - сколько ждать после failures неудач подряд и блокировка ли это.
+// Первая ошибка бесплатно, дальше 1с, 2с, 4с... но не дольше lockout,
+// на maxAttempts неудаче аккаунт блокируется на lockout
+func loginBackoff(failures int64, maxAttempts int, lockout time.Duration) (time.Duration, bool) {
+	if failures >= int64(maxAttempts) {
+		return lockout, true
+	}
+	if failures <= 1 {
+		return 0, false
+	}
+
+	// Сдвиг больше 30 бит заведомо длиннее любого разумного lockout, заодно без переполнения
+	shift := failures - 2
+	if shift > 30 {
+		return lockout, false
+	}
+	return min(time.Second<<shift, lockout), false
+}
+
+// Окно IP счетчика фиксированное, как в rate limiter, чтобы знать время сброса
+func ipFailuresKey(ip string, windowStart time.Time) string {
+	return fmt.Sprintf("login_failures_ip:%s:%d", ip, windowStart.Unix())
+}
+
+func loginFailuresKey(userID uuid.UUID) string {
+	return fmt.Sprintf("login_failures:%s", userID)
+}
+
+func loginBlockKey(userID uuid.UUID) string {
+	return fmt.Sprintf("login_block:%s", userID)
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	const maxAttempts = 5
+	lockout := 15 * time.Minute
+
+	tests := []struct {
+		name       string
+		failures   int64
+		maxAtt     int
+		lockout    time.Duration
+		wantDelay  time.Duration
+		wantLocked bool
+	}{
+		{"first failure is free", 1, maxAttempts, lockout, 0, false},
+		{"second failure waits 1s", 2, maxAttempts, lockout, time.Second, false},
+		{"third failure waits 2s", 3, maxAttempts, lockout, 2 * time.Second, false},
+		{"fourth failure waits 4s", 4, maxAttempts, lockout, 4 * time.Second, false},
+		{"max attempts locks", 5, maxAttempts, lockout, lockout, true},
+		{"over max attempts locks", 7, maxAttempts, lockout, lockout, true},
+		{"delay capped by lockout", 10, 20, 30 * time.Second, 30 * time.Second, false},
+		{"huge shift does not overflow", 100, 1000, lockout, lockout, false},
+		{"single attempt allowed", 1, 1, lockout, lockout, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, locked := loginBackoff(tt.failures, tt.maxAtt, tt.lockout)
+			if delay != tt.wantDelay || locked != tt.wantLocked {
+				t.Fatalf("loginBackoff(%d, %d, %s) = (%s, %v), want (%s, %v)",
+					tt.failures, tt.maxAtt, tt.lockout, delay, locked, tt.wantDelay, tt.wantLocked)
+			}
+		})
+	}
+}
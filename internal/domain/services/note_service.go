@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/repositories"
+
+	"github.com/google/uuid"
+)
+
+type noteService struct {
+	noteRepo repositories.NoteRepository
+}
+
+type NoteService interface {
+	CreateNote(ctx context.Context, req *entities.NoteCreateReq, userID uuid.UUID) (*entities.Note, error)
+	GetNote(ctx context.Context, noteID, userID uuid.UUID) (*entities.Note, error)
+	ListNotes(ctx context.Context, userID uuid.UUID, filter *entities.NoteListFilter, page entities.PageRequest) (*entities.Page[entities.Note], error)
+	UpdateNote(ctx context.Context, req *entities.NoteUpdateReq, noteID, userID uuid.UUID) (*entities.Note, error)
+	DeleteNote(ctx context.Context, noteID, userID uuid.UUID) error
+}
+
+func NewNoteService(noteRepo repositories.NoteRepository) NoteService {
+	return &noteService{noteRepo: noteRepo}
+}
+
+func (s *noteService) CreateNote(ctx context.Context, req *entities.NoteCreateReq, userID uuid.UUID) (*entities.Note, error) {
+	title, err := trimTitle(req.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &entities.Note{
+		UserID: userID,
+		Title:  title,
+		Body:   req.Body,
+	}
+
+	if err := s.noteRepo.Create(note); err != nil {
+		return nil, err
+	}
+
+	return note, nil
+}
+
+func (s *noteService) GetNote(ctx context.Context, noteID, userID uuid.UUID) (*entities.Note, error) {
+	return s.noteRepo.GetByID(noteID, userID)
+}
+
+func (s *noteService) ListNotes(ctx context.Context, userID uuid.UUID, filter *entities.NoteListFilter, page entities.PageRequest) (*entities.Page[entities.Note], error) {
+	if filter == nil {
+		filter = &entities.NoteListFilter{}
+	}
+	return s.noteRepo.List(userID, filter, page)
+}
+
+func (s *noteService) UpdateNote(ctx context.Context, req *entities.NoteUpdateReq, noteID, userID uuid.UUID) (*entities.Note, error) {
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		title, err := trimTitle(*req.Title)
+		if err != nil {
+			return nil, err
+		}
+		updates["title"] = title
+	}
+	if req.Body != nil {
+		updates["body"] = *req.Body
+	}
+
+	if len(updates) > 0 {
+		if err := s.noteRepo.Update(noteID, userID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.noteRepo.GetByID(noteID, userID)
+}
+
+func (s *noteService) DeleteNote(ctx context.Context, noteID, userID uuid.UUID) error {
+	return s.noteRepo.Delete(noteID, userID)
+}
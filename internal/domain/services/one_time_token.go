@@ -0,0 +1,24 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// generateOneTimeToken - случайный токен для ссылки из письма и его sha256.
+// В Redis кладем только хэш, поэтому утечка Redis не дает готовых ссылок
+func generateOneTimeToken() (token, tokenHash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashOneTimeToken(token), nil
+}
+
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/cache"
+	"rest-api-notes/internal/infrastructure/notification"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type passwordResetService struct {
+	redisClient cache.RedisClient
+	mailer      notification.Mailer
+	resetURL    string
+	ttl         time.Duration
+}
+
+// PasswordResetService - одноразовые токены сброса пароля
+type PasswordResetService interface {
+	// SendResetLink выпускает новый токен (старый перестает работать) и отправляет ссылку на почту
+	SendResetLink(ctx context.Context, user *entities.User) error
+	// ConsumeToken проверяет токен и сразу удаляет его, повторно он уже не сработает
+	ConsumeToken(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+func NewPasswordResetService(redisClient cache.RedisClient, mailer notification.Mailer,
+	resetURL string, ttl time.Duration) PasswordResetService {
+	return &passwordResetService{
+		redisClient: redisClient,
+		mailer:      mailer,
+		resetURL:    resetURL,
+		ttl:         ttl,
+	}
+}
+
+func (s *passwordResetService) SendResetLink(ctx context.Context, user *entities.User) error {
+	token, tokenHash, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	// У пользователя действует только последняя ссылка
+	var prevHash string
+	if err := s.redisClient.GetStruct(ctx, passwordResetUserKey(user.ID), &prevHash); err == nil {
+		if err := s.redisClient.Delete(ctx, passwordResetKey(prevHash)); err != nil {
+			return err
+		}
+	}
+
+	data := entities.PasswordResetToken{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	}
+	if err := s.redisClient.SetStruct(ctx, passwordResetKey(tokenHash), &data, s.ttl); err != nil {
+		return err
+	}
+	if err := s.redisClient.SetStruct(ctx, passwordResetUserKey(user.ID), tokenHash, s.ttl); err != nil {
+		return err
+	}
+
+	link := s.resetURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Someone requested a password reset for your account %s.\r\n\r\n"+
+		"To set a new password, open this link within %d minutes:\r\n%s\r\n\r\n"+
+		"If you did not request this, ignore this email, your password will not change.",
+		user.Username, int(s.ttl.Minutes()), link)
+
+	return s.mailer.Send(ctx, user.Email, "Password reset", body)
+}
+
+func (s *passwordResetService) ConsumeToken(ctx context.Context, token string) (uuid.UUID, error) {
+	var data entities.PasswordResetToken
+	if err := s.redisClient.GetDelStruct(ctx, passwordResetKey(hashOneTimeToken(token)), &data); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return uuid.Nil, entities.ErrInvalidPasswordResetToken
+		}
+		return uuid.Nil, err
+	}
+
+	if time.Now().Unix() > data.ExpiresAt {
+		return uuid.Nil, entities.ErrInvalidPasswordResetToken
+	}
+
+	if err := s.redisClient.Delete(ctx, passwordResetUserKey(data.UserID)); err != nil {
+		return uuid.Nil, err
+	}
+
+	return data.UserID, nil
+}
+
+func passwordResetKey(tokenHash string) string {
+	return fmt.Sprintf("password_reset:%s", tokenHash)
+}
+
+func passwordResetUserKey(userID uuid.UUID) string {
+	return fmt.Sprintf("password_reset_user:%s", userID)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"math/big"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/repositories"
+	"rest-api-notes/internal/infrastructure/auth"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodesCount = 10
+	recoveryCodeLength = 10
+	// Без 0/o, 1/l/i, чтобы код было проще переписать с бумажки
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+type recoveryCodeService struct {
+	repo repositories.RecoveryCodeRepository
+	pS   auth.PasswordService
+}
+
+type RecoveryCodeService interface {
+	// Generate заменяет все старые коды новым набором и возвращает его в открытом виде
+	Generate(ctx context.Context, userID uuid.UUID) ([]string, error)
+	Use(ctx context.Context, userID uuid.UUID, code string) error
+	CountRemaining(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteAll(ctx context.Context, userID uuid.UUID) error
+}
+
+func NewRecoveryCodeService(repo repositories.RecoveryCodeRepository, pS auth.PasswordService) RecoveryCodeService {
+	return &recoveryCodeService{repo: repo, pS: pS}
+}
+
+// IsRecoveryCode - коды из Telegram и TOTP только из цифр, а в recovery коде всегда есть буквы
+func IsRecoveryCode(code string) bool {
+	normalized := normalizeRecoveryCode(code)
+	return len(normalized) == recoveryCodeLength && strings.IndexFunc(normalized, func(r rune) bool {
+		return r < '0' || r > '9'
+	}) != -1
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func (s *recoveryCodeService) Generate(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for len(codes) < recoveryCodesCount {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if !IsRecoveryCode(code) {
+			continue
+		}
+
+		hash, err := s.pS.HashPassword(code)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hash)
+	}
+
+	if err := s.repo.Replace(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *recoveryCodeService) Use(ctx context.Context, userID uuid.UUID, code string) error {
+	code = normalizeRecoveryCode(code)
+
+	codes, err := s.repo.ListUnused(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, stored := range codes {
+		if err := s.pS.ComparePasswords(stored.CodeHash, code); err != nil {
+			continue
+		}
+
+		used, err := s.repo.MarkUsed(stored.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return entities.Err2FACodeInvalid
+		}
+
+		log.Printf("SECURITY: recovery code used user_id=%s remaining=%d", userID, len(codes)-1)
+		return nil
+	}
+
+	return entities.Err2FACodeInvalid
+}
+
+func (s *recoveryCodeService) CountRemaining(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.repo.CountUnused(userID)
+}
+
+func (s *recoveryCodeService) DeleteAll(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.DeleteAll(userID)
+}
+
+func randomRecoveryCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := 0; i < recoveryCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"abcde-fgh23", "abcdefgh23"},
+		{"ABCDE-FGH23", "abcdefgh23"},
+		{"  abcde-fgh23\n", "abcdefgh23"},
+		{"abcde fgh23", "abcdefgh23"},
+		{"ab-cd-ef-gh-23", "abcdefgh23"},
+		{"abcdefgh23", "abcdefgh23"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.in); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsRecoveryCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"formatted", "abcde-fgh23", true},
+		{"upper case with spaces", " ABCDE FGH23 ", true},
+		{"without dash", "abcdefgh23", true},
+		{"telegram code", "1234", false},
+		{"totp code", "123456", false},
+		{"ten digits", "12345-67890", false},
+		{"too short", "abcde-fgh2", false},
+		{"too long", "abcde-fgh234", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRecoveryCode(tt.code); got != tt.want {
+				t.Fatalf("IsRecoveryCode(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandomRecoveryCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			t.Fatalf("randomRecoveryCode: %v", err)
+		}
+		if len(code) != recoveryCodeLength {
+			t.Fatalf("randomRecoveryCode() = %q, want length %d", code, recoveryCodeLength)
+		}
+		if i := strings.IndexFunc(code, func(r rune) bool { return !strings.ContainsRune(recoveryCodeAlphabet, r) }); i != -1 {
+			t.Fatalf("randomRecoveryCode() = %q, symbol %q is not in the alphabet", code, code[i])
+		}
+	}
+}
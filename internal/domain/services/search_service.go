@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/repositories"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type searchService struct {
+	searchRepo repositories.SearchRepository
+}
+
+type SearchService interface {
+	Search(ctx context.Context, userID uuid.UUID, rawQuery string, limit int) (*entities.SearchRes, error)
+}
+
+func NewSearchService(searchRepo repositories.SearchRepository) SearchService {
+	return &searchService{searchRepo: searchRepo}
+}
+
+func (s *searchService) Search(ctx context.Context, userID uuid.UUID, rawQuery string, limit int) (*entities.SearchRes, error) {
+	query := ParseSearchQuery(rawQuery)
+	if query.Text == "" && len(query.Tags) == 0 {
+		return nil, entities.ErrEmptySearchQuery
+	}
+
+	results, err := s.searchRepo.Search(userID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.SearchRes{Query: rawQuery, Results: results}, nil
+}
+
+// ParseSearchQuery вытаскивает tag:foo токены, остальное оставляет как есть
+// для websearch_to_tsquery ("фраза в кавычках", -исключение, OR).
+// tag: внутри кавычек считается обычным текстом
+func ParseSearchQuery(raw string) *entities.SearchQuery {
+	query := &entities.SearchQuery{}
+	seenTags := map[string]bool{}
+
+	var text []string
+	for _, token := range tokenizeSearchQuery(raw) {
+		if name, ok := strings.CutPrefix(token, "tag:"); ok {
+			name = NormalizeTagName(name)
+			if name != "" && !seenTags[name] {
+				seenTags[name] = true
+				query.Tags = append(query.Tags, name)
+			}
+			continue
+		}
+		text = append(text, token)
+	}
+
+	query.Text = strings.Join(text, " ")
+	return query
+}
+
+// tokenizeSearchQuery режет по пробелам, не разрывая "фразы в кавычках"
+func tokenizeSearchQuery(raw string) []string {
+	var (
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+	)
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range strings.TrimSpace(raw) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
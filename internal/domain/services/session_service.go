@@ -2,41 +2,78 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"rest-api-notes/internal/domain/entities"
 	"rest-api-notes/internal/infrastructure/cache"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type sessionService struct {
-	redisClient cache.RedisClient
-	ttl         time.Duration
+	redisClient    cache.RedisClient
+	ttl            time.Duration
+	accessTTL      time.Duration
+	max2FAAttempts int
+	max2FAFailures int
+	twoFALockout   time.Duration
 }
 
 type SessionService interface {
-	CreateSession(ctx context.Context, userID uuid.UUID, sessionID, accessToken, refreshToken, userAgent, ip string) (*entities.Session, error)
+	CreateSession(ctx context.Context, userID uuid.UUID, sessionID, familyID, accessToken, refreshToken, userAgent, ip string) (*entities.Session, error)
 	GetSession(ctx context.Context, userID uuid.UUID, sessionID string) (*entities.Session, error)
 	DeleteSession(ctx context.Context, userID uuid.UUID, sessionID string) error
 	UpdateSession(ctx context.Context, userID uuid.UUID, sessionID string, session *entities.Session) error
 	IsSessionValid(ctx context.Context, userID uuid.UUID, sessionID, refreshToken string) (bool, error)
 	GetAllUserSessions(ctx context.Context, userID uuid.UUID) (*[]entities.Session, error)
+	DeleteAllUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID string) error
+	IsSessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	SaveTokenFamily(ctx context.Context, family *entities.TokenFamily) error
+	GetTokenFamily(ctx context.Context, familyID string) (*entities.TokenFamily, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	IsTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error)
+	// RevokeUserTokenFamilies отзывает семейства всех сессий пользователя вместе с сессиями
+	RevokeUserTokenFamilies(ctx context.Context, userID uuid.UUID) error
+	// ClaimRefreshToken атомарно помечает refresh токен использованным.
+	// false - токен уже был обменян (в том числе параллельным запросом)
+	ClaimRefreshToken(ctx context.Context, refreshToken string, session *entities.Session) (bool, error)
+	GetRotatedRefreshToken(ctx context.Context, refreshToken string) (*entities.RotatedRefreshToken, error)
 	Save2FACode(ctx context.Context, userID uuid.UUID, code string, context entities.TwoFASessionContext, token ...string) error
 	Delete2FACode(ctx context.Context, userID uuid.UUID, context entities.TwoFASessionContext) error
 	Get2FAData(ctx context.Context, userID uuid.UUID, context entities.TwoFASessionContext) (*entities.TwoFASessionData, error)
 	Verify2FACode(ctx context.Context, userID uuid.UUID, code string, context entities.TwoFASessionContext) (*entities.TwoFASessionData, error)
+	Register2FAFailure(ctx context.Context, userID uuid.UUID, context entities.TwoFASessionContext) error
+	// Check2FALock - ошибка, если проверка второго фактора у пользователя заблокирована
+	Check2FALock(ctx context.Context, userID uuid.UUID) error
+	SavePendingTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	GetPendingTOTPSecret(ctx context.Context, userID uuid.UUID) (string, error)
+	DeletePendingTOTPSecret(ctx context.Context, userID uuid.UUID) error
+	MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
 }
 
-func NewSessionService(redisClient cache.RedisClient, ttl time.Duration) SessionService {
+// accessTTL - время жизни access токена, столько же живет запись об отозванной сессии.
+// max2FAAttempts - сколько неверных вводов выдерживает один 2FA код.
+// max2FAFailures - сколько неверных кодов пользователя за twoFALockout (по всем кодам
+// и IP) приводит к блокировке проверки на twoFALockout
+func NewSessionService(redisClient cache.RedisClient, ttl, accessTTL time.Duration, max2FAAttempts,
+	max2FAFailures int, twoFALockout time.Duration) SessionService {
 	return &sessionService{
-		redisClient: redisClient,
-		ttl:         ttl,
+		redisClient:    redisClient,
+		ttl:            ttl,
+		accessTTL:      accessTTL,
+		max2FAAttempts: max2FAAttempts,
+		max2FAFailures: max2FAFailures,
+		twoFALockout:   twoFALockout,
 	}
 }
 
-func (s *sessionService) CreateSession(ctx context.Context, userID uuid.UUID, sessionID, accessToken, refreshToken, userAgent, ip string) (*entities.Session, error) {
+func (s *sessionService) CreateSession(ctx context.Context, userID uuid.UUID, sessionID, familyID, accessToken, refreshToken, userAgent, ip string) (*entities.Session, error) {
 	expiresAt := time.Now().Add(s.ttl).Unix()
 
 	session := &entities.Session{
@@ -47,6 +84,8 @@ func (s *sessionService) CreateSession(ctx context.Context, userID uuid.UUID, se
 		UserAgent:    userAgent,
 		IP:           ip,
 		ExpiresAt:    expiresAt,
+		CreatedAt:    time.Now().Unix(),
+		FamilyID:     familyID,
 	}
 
 	key := fmt.Sprintf("session:%s:%s", userID, sessionID)
@@ -64,7 +103,7 @@ func (s *sessionService) GetSession(ctx context.Context, userID uuid.UUID, sessi
 	key := fmt.Sprintf("session:%s:%s", userID, sessionID)
 	var session entities.Session
 	if err := s.redisClient.GetStruct(ctx, key, &session); err != nil {
-		if err.Error() == "redis: nil" {
+		if errors.Is(err, cache.ErrNotFound) {
 			return nil, nil
 		}
 		return nil, err
@@ -90,11 +129,23 @@ func (s *sessionService) IsSessionValid(ctx context.Context, userID uuid.UUID, s
 	return true, nil
 }
 
+// DeleteSession удаляет сессию и заносит ее в denylist, чтобы уже выданный
+// access токен этой сессии перестал приниматься сразу, а не по истечении exp
 func (s *sessionService) DeleteSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	revokedKey := fmt.Sprintf("revoked_session:%s", sessionID)
+	if err := s.redisClient.SetStruct(ctx, revokedKey, true, s.accessTTL); err != nil {
+		return err
+	}
+
 	key := fmt.Sprintf("session:%s:%s", userID, sessionID)
 	return s.redisClient.Delete(ctx, key)
 }
 
+func (s *sessionService) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	key := fmt.Sprintf("revoked_session:%s", sessionID)
+	return s.redisClient.Exists(ctx, key)
+}
+
 func (s *sessionService) UpdateSession(ctx context.Context, userID uuid.UUID, sessionID string, session *entities.Session) error {
 	key := fmt.Sprintf("session:%s:%s", userID, sessionID)
 	return s.redisClient.SetStruct(ctx, key, session, s.ttl)
@@ -110,6 +161,129 @@ func (s *sessionService) GetAllUserSessions(ctx context.Context, userID uuid.UUI
 	return &sessions, nil
 }
 
+// DeleteAllUserSessions удаляет все сессии пользователя, кроме exceptSessionID (если не пустой)
+func (s *sessionService) DeleteAllUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID string) error {
+	sessions, err := s.GetAllUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range *sessions {
+		if session.SessionID == exceptSessionID {
+			continue
+		}
+		if err := s.DeleteSession(ctx, userID, session.SessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TOKEN FAMILIES
+
+func (s *sessionService) SaveTokenFamily(ctx context.Context, family *entities.TokenFamily) error {
+	key := fmt.Sprintf("token_family:%s", family.FamilyID)
+	return s.redisClient.SetStruct(ctx, key, family, s.ttl)
+}
+
+func (s *sessionService) GetTokenFamily(ctx context.Context, familyID string) (*entities.TokenFamily, error) {
+	key := fmt.Sprintf("token_family:%s", familyID)
+	var family entities.TokenFamily
+	if err := s.redisClient.GetStruct(ctx, key, &family); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &family, nil
+}
+
+// RevokeTokenFamily убивает все сессии семейства и саму семью.
+// Следы ротированных токенов остаются до TTL, чтобы повторы тоже ловились
+func (s *sessionService) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	// Метка нужна ротации, которая идет параллельно: новую сессию она создаст уже после
+	// того, как мы прочитаем семью, и должна удалить ее сама
+	if err := s.redisClient.SetStruct(ctx, revokedTokenFamilyKey(familyID), true, s.ttl); err != nil {
+		return err
+	}
+
+	family, err := s.GetTokenFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+	if family == nil {
+		return nil
+	}
+
+	// У семейств, созданных до SessionIDs, известна только текущая сессия
+	sessionIDs := family.SessionIDs
+	if !slices.Contains(sessionIDs, family.CurrentSessionID) {
+		sessionIDs = append(sessionIDs, family.CurrentSessionID)
+	}
+	for _, sessionID := range sessionIDs {
+		if err := s.DeleteSession(ctx, family.UserID, sessionID); err != nil {
+			return err
+		}
+	}
+
+	return s.redisClient.Delete(ctx, fmt.Sprintf("token_family:%s", familyID))
+}
+
+func (s *sessionService) RevokeUserTokenFamilies(ctx context.Context, userID uuid.UUID) error {
+	sessions, err := s.GetAllUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range *sessions {
+		if session.FamilyID != "" {
+			if err := s.RevokeTokenFamily(ctx, session.FamilyID); err != nil {
+				return err
+			}
+		}
+		// Семья могла истечь раньше сессии, а у старых сессий ее нет вовсе
+		if err := s.DeleteSession(ctx, userID, session.SessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sessionService) IsTokenFamilyRevoked(ctx context.Context, familyID string) (bool, error) {
+	return s.redisClient.Exists(ctx, revokedTokenFamilyKey(familyID))
+}
+
+func revokedTokenFamilyKey(familyID string) string {
+	return fmt.Sprintf("revoked_token_family:%s", familyID)
+}
+
+func (s *sessionService) ClaimRefreshToken(ctx context.Context, refreshToken string, session *entities.Session) (bool, error) {
+	rotated := &entities.RotatedRefreshToken{
+		FamilyID:  session.FamilyID,
+		UserID:    session.UserID,
+		SessionID: session.SessionID,
+		RotatedAt: time.Now().Unix(),
+	}
+	return s.redisClient.SetNXStruct(ctx, rotatedRefreshTokenKey(refreshToken), rotated, s.ttl)
+}
+
+func (s *sessionService) GetRotatedRefreshToken(ctx context.Context, refreshToken string) (*entities.RotatedRefreshToken, error) {
+	var rotated entities.RotatedRefreshToken
+	if err := s.redisClient.GetStruct(ctx, rotatedRefreshTokenKey(refreshToken), &rotated); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rotated, nil
+}
+
+// В ключ кладем хэш, сами refresh токены в Redis лишний раз не храним
+func rotatedRefreshTokenKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return fmt.Sprintf("rotated_refresh:%s", hex.EncodeToString(sum[:]))
+}
+
 // 2FA LOGIC
 
 func (s *sessionService) Save2FACode(ctx context.Context, userID uuid.UUID, code string, context entities.TwoFASessionContext, token ...string) error {
@@ -128,6 +302,11 @@ func (s *sessionService) Save2FACode(ctx context.Context, userID uuid.UUID, code
 			data.Token = token[0]
 		}
 
+		// У нового кода счетчик попыток начинается с нуля
+		if err := s.redisClient.Delete(ctx, twoFAAttemptsKey(userID, context)); err != nil {
+			return err
+		}
+
 		return s.redisClient.SetStruct(ctx, key, data, 5*time.Minute)
 	}
 
@@ -144,6 +323,9 @@ func (s *sessionService) Save2FACode(ctx context.Context, userID uuid.UUID, code
 
 func (s *sessionService) Delete2FACode(ctx context.Context, userID uuid.UUID, context entities.TwoFASessionContext) error {
 	key := fmt.Sprintf("2fa_code:%s:%s", userID, context)
+	if err := s.redisClient.Delete(ctx, twoFAAttemptsKey(userID, context)); err != nil {
+		return err
+	}
 	return s.redisClient.Delete(ctx, key)
 }
 
@@ -169,8 +351,8 @@ func (s *sessionService) Verify2FACode(ctx context.Context, userID uuid.UUID, co
 		return nil, err
 	}
 
-	if data.Code != code {
-		return nil, entities.Err2FACodeInvalid
+	if subtle.ConstantTimeCompare([]byte(data.Code), []byte(code)) != 1 {
+		return nil, s.Register2FAFailure(ctx, userID, context)
 	}
 
 	// Удаляем код после успешной проверки
@@ -180,3 +362,119 @@ func (s *sessionService) Verify2FACode(ctx context.Context, userID uuid.UUID, co
 
 	return data, nil
 }
+
+// Register2FAFailure учитывает неверный ввод кода. Возвращает ошибку с оставшимися
+// попытками, а когда они кончились - удаляет код, дальше нужно запрашивать новый.
+// Попытки кода обнуляются с новым кодом, поэтому отдельно считаем неудачи пользователя:
+// иначе перевыпуск кода через повторный вход с новых IP давал бы бесконечный перебор
+func (s *sessionService) Register2FAFailure(ctx context.Context, userID uuid.UUID, context entities.TwoFASessionContext) error {
+	if err := s.registerUser2FAFailure(ctx, userID, context); err != nil {
+		return err
+	}
+
+	key := twoFAAttemptsKey(userID, context)
+
+	attempts, err := s.redisClient.IncrExpire(ctx, key, 5*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	remaining := s.max2FAAttempts - int(attempts)
+	if remaining <= 0 {
+		log.Printf("SECURITY: 2FA code invalidated after %d failed attempts user_id=%s context=%s", attempts, userID, context)
+		if err := s.Delete2FACode(ctx, userID, context); err != nil {
+			return err
+		}
+		return entities.Err2FATooManyAttempts
+	}
+
+	return entities.NewInvalid2FACodeError(remaining)
+}
+
+// registerUser2FAFailure считает неверные коды пользователя в окне twoFALockout
+// и блокирует проверку, когда их набралось max2FAFailures
+func (s *sessionService) registerUser2FAFailure(ctx context.Context, userID uuid.UUID, context entities.TwoFASessionContext) error {
+	key := twoFAFailuresKey(userID)
+
+	failures, err := s.redisClient.IncrExpire(ctx, key, s.twoFALockout)
+	if err != nil {
+		return err
+	}
+	if failures < int64(s.max2FAFailures) {
+		return nil
+	}
+
+	until := time.Now().Add(s.twoFALockout)
+	log.Printf("SECURITY: 2FA verification locked after %d failed codes user_id=%s until=%s",
+		failures, userID, until.UTC().Format(time.RFC3339))
+
+	block := entities.LoginBlock{UntilMs: until.UnixMilli(), Locked: true}
+	if err := s.redisClient.SetStruct(ctx, twoFALockKey(userID), &block, s.twoFALockout); err != nil {
+		return err
+	}
+	if err := s.redisClient.Delete(ctx, key); err != nil {
+		return err
+	}
+	if err := s.Delete2FACode(ctx, userID, context); err != nil {
+		return err
+	}
+	return entities.NewTwoFALockedError(until)
+}
+
+func (s *sessionService) Check2FALock(ctx context.Context, userID uuid.UUID) error {
+	var block entities.LoginBlock
+	if err := s.redisClient.GetStruct(ctx, twoFALockKey(userID), &block); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	until := time.UnixMilli(block.UntilMs)
+	if !time.Now().Before(until) {
+		return nil
+	}
+	return entities.NewTwoFALockedError(until)
+}
+
+func twoFAAttemptsKey(userID uuid.UUID, context entities.TwoFASessionContext) string {
+	return fmt.Sprintf("2fa_attempts:%s:%s", userID, context)
+}
+
+// Неудачи пользователя по всем кодам, с выпуском нового кода не сбрасываются
+func twoFAFailuresKey(userID uuid.UUID) string {
+	return fmt.Sprintf("2fa_failures:%s", userID)
+}
+
+func twoFALockKey(userID uuid.UUID) string {
+	return fmt.Sprintf("2fa_lock:%s", userID)
+}
+
+// TOTP LOGIC
+
+// Секрет до подтверждения первым кодом живет только в Redis
+func (s *sessionService) SavePendingTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	key := fmt.Sprintf("totp_pending:%s", userID)
+	return s.redisClient.SetStruct(ctx, key, secret, 10*time.Minute)
+}
+
+func (s *sessionService) GetPendingTOTPSecret(ctx context.Context, userID uuid.UUID) (string, error) {
+	key := fmt.Sprintf("totp_pending:%s", userID)
+	var secret string
+	if err := s.redisClient.GetStruct(ctx, key, &secret); err != nil {
+		return "", entities.ErrTOTPEnrollmentNotFound
+	}
+	return secret, nil
+}
+
+func (s *sessionService) DeletePendingTOTPSecret(ctx context.Context, userID uuid.UUID) error {
+	key := fmt.Sprintf("totp_pending:%s", userID)
+	return s.redisClient.Delete(ctx, key)
+}
+
+// MarkTOTPStepUsed не дает повторно использовать один и тот же код в его окне.
+// false - код этого интервала уже принимали
+func (s *sessionService) MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	key := fmt.Sprintf("totp_used:%s:%d", userID, step)
+	return s.redisClient.SetNX(ctx, key, 1, 3*time.Minute)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/repositories"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type tagService struct {
+	tagRepo repositories.TagRepository
+}
+
+type TagService interface {
+	CreateTag(ctx context.Context, req *entities.TagCreateReq, userID uuid.UUID) (*entities.Tag, error)
+	ListTags(ctx context.Context, userID uuid.UUID) ([]entities.Tag, error)
+	RenameTag(ctx context.Context, req *entities.TagRenameReq, tagID, userID uuid.UUID) (*entities.Tag, error)
+	DeleteTag(ctx context.Context, tagID, userID uuid.UUID) error
+	MergeTags(ctx context.Context, sourceID, targetID, userID uuid.UUID) (*entities.Tag, error)
+	AttachTags(ctx context.Context, req *entities.TagBulkReq, userID uuid.UUID) error
+	DetachTags(ctx context.Context, req *entities.TagBulkReq, userID uuid.UUID) error
+}
+
+func NewTagService(tagRepo repositories.TagRepository) TagService {
+	return &tagService{tagRepo: tagRepo}
+}
+
+// NormalizeTagName - имена тегов храним в нижнем регистре без пробелов по краям
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (s *tagService) CreateTag(ctx context.Context, req *entities.TagCreateReq, userID uuid.UUID) (*entities.Tag, error) {
+	name := NormalizeTagName(req.Name)
+	if name == "" {
+		return nil, entities.ErrEmptyTagName
+	}
+
+	tag := &entities.Tag{
+		UserID: userID,
+		Name:   name,
+	}
+
+	if err := s.tagRepo.Create(tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (s *tagService) ListTags(ctx context.Context, userID uuid.UUID) ([]entities.Tag, error) {
+	return s.tagRepo.List(userID)
+}
+
+func (s *tagService) RenameTag(ctx context.Context, req *entities.TagRenameReq, tagID, userID uuid.UUID) (*entities.Tag, error) {
+	name := NormalizeTagName(req.Name)
+	if name == "" {
+		return nil, entities.ErrEmptyTagName
+	}
+
+	if err := s.tagRepo.Rename(tagID, userID, name); err != nil {
+		return nil, err
+	}
+
+	return s.tagRepo.GetByID(tagID, userID)
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, tagID, userID uuid.UUID) error {
+	return s.tagRepo.Transaction(func(repo repositories.TagRepository) error {
+		return repo.Delete(tagID, userID)
+	})
+}
+
+// MergeTags переносит все задачи и заметки с source на target и удаляет source.
+// Все в одной транзакции, чтобы не остаться с половиной перенесенных связей
+func (s *tagService) MergeTags(ctx context.Context, sourceID, targetID, userID uuid.UUID) (*entities.Tag, error) {
+	if sourceID == targetID {
+		return nil, entities.ErrTagMergeIntoSelf
+	}
+
+	var target *entities.Tag
+	err := s.tagRepo.Transaction(func(repo repositories.TagRepository) error {
+		if _, err := repo.GetByID(sourceID, userID); err != nil {
+			return err
+		}
+
+		var err error
+		if target, err = repo.GetByID(targetID, userID); err != nil {
+			return err
+		}
+
+		if err := repo.MoveAssociations(sourceID, targetID); err != nil {
+			return err
+		}
+
+		return repo.Delete(sourceID, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func (s *tagService) AttachTags(ctx context.Context, req *entities.TagBulkReq, userID uuid.UUID) error {
+	tagIDs, taskIDs, noteIDs := uniqueIDs(req.TagIDs), uniqueIDs(req.TaskIDs), uniqueIDs(req.NoteIDs)
+
+	return s.tagRepo.Transaction(func(repo repositories.TagRepository) error {
+		if err := s.checkOwnership(repo, userID, tagIDs, taskIDs, noteIDs); err != nil {
+			return err
+		}
+
+		if len(taskIDs) > 0 {
+			if err := repo.AttachToTasks(userID, tagIDs, taskIDs); err != nil {
+				return err
+			}
+		}
+		if len(noteIDs) > 0 {
+			if err := repo.AttachToNotes(userID, tagIDs, noteIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *tagService) DetachTags(ctx context.Context, req *entities.TagBulkReq, userID uuid.UUID) error {
+	tagIDs, taskIDs, noteIDs := uniqueIDs(req.TagIDs), uniqueIDs(req.TaskIDs), uniqueIDs(req.NoteIDs)
+
+	return s.tagRepo.Transaction(func(repo repositories.TagRepository) error {
+		if err := s.checkOwnership(repo, userID, tagIDs, taskIDs, noteIDs); err != nil {
+			return err
+		}
+
+		if len(taskIDs) > 0 {
+			if err := repo.DetachFromTasks(userID, tagIDs, taskIDs); err != nil {
+				return err
+			}
+		}
+		if len(noteIDs) > 0 {
+			if err := repo.DetachFromNotes(userID, tagIDs, noteIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkOwnership проверяет, что все переданные теги, задачи и заметки принадлежат пользователю
+func (s *tagService) checkOwnership(repo repositories.TagRepository, userID uuid.UUID, tagIDs, taskIDs, noteIDs []uuid.UUID) error {
+	count, err := repo.CountUserTags(userID, tagIDs)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(tagIDs)) {
+		return entities.ErrTagNotFound
+	}
+
+	if len(taskIDs) > 0 {
+		count, err := repo.CountUserTasks(userID, taskIDs)
+		if err != nil {
+			return err
+		}
+		if count != int64(len(taskIDs)) {
+			return entities.ErrTaskNotFound
+		}
+	}
+
+	if len(noteIDs) > 0 {
+		count, err := repo.CountUserNotes(userID, noteIDs)
+		if err != nil {
+			return err
+		}
+		if count != int64(len(noteIDs)) {
+			return entities.ErrNoteNotFound
+		}
+	}
+
+	return nil
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
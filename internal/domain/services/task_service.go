@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/domain/repositories"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type taskService struct {
+	taskRepo repositories.TaskRepository
+}
+
+type TaskService interface {
+	CreateTask(ctx context.Context, req *entities.TaskCreateReq, userID uuid.UUID) (*entities.Task, error)
+	GetTask(ctx context.Context, taskID, userID uuid.UUID) (*entities.Task, error)
+	ListTasks(ctx context.Context, userID uuid.UUID, filter *entities.TaskListFilter, page entities.PageRequest) (*entities.Page[entities.Task], error)
+	UpdateTask(ctx context.Context, req *entities.TaskUpdateReq, taskID, userID uuid.UUID) (*entities.Task, error)
+	DeleteTask(ctx context.Context, taskID, userID uuid.UUID) error
+	TransitionTask(ctx context.Context, req *entities.TaskTransitionReq, taskID, userID uuid.UUID) (*entities.Task, error)
+
+	CreateSubTask(ctx context.Context, req *entities.SubTaskCreateReq, taskID, userID uuid.UUID) (*entities.SubTask, error)
+	GetSubTask(ctx context.Context, subTaskID, taskID, userID uuid.UUID) (*entities.SubTask, error)
+	ListSubTasks(ctx context.Context, taskID, userID uuid.UUID) ([]entities.SubTask, error)
+	UpdateSubTask(ctx context.Context, req *entities.SubTaskUpdateReq, subTaskID, taskID, userID uuid.UUID) (*entities.SubTask, error)
+	DeleteSubTask(ctx context.Context, subTaskID, taskID, userID uuid.UUID) error
+}
+
+func NewTaskService(taskRepo repositories.TaskRepository) TaskService {
+	return &taskService{taskRepo: taskRepo}
+}
+
+func (s *taskService) CreateTask(ctx context.Context, req *entities.TaskCreateReq, userID uuid.UUID) (*entities.Task, error) {
+	title, err := trimTitle(req.Title)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(req.StartAt, req.DueAt); err != nil {
+		return nil, err
+	}
+
+	task := &entities.Task{
+		UserID:      userID,
+		Title:       title,
+		Description: req.Description,
+		StartAt:     req.StartAt,
+		DueAt:       req.DueAt,
+		SubTasks:    []entities.SubTask{},
+	}
+	if req.Priority != nil {
+		task.Priority = *req.Priority
+	}
+
+	if err := s.taskRepo.Create(task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (s *taskService) GetTask(ctx context.Context, taskID, userID uuid.UUID) (*entities.Task, error) {
+	return s.taskRepo.GetByID(taskID, userID)
+}
+
+func (s *taskService) ListTasks(ctx context.Context, userID uuid.UUID, filter *entities.TaskListFilter, page entities.PageRequest) (*entities.Page[entities.Task], error) {
+	if filter == nil {
+		filter = &entities.TaskListFilter{}
+	}
+	return s.taskRepo.List(userID, filter, page)
+}
+
+func (s *taskService) UpdateTask(ctx context.Context, req *entities.TaskUpdateReq, taskID, userID uuid.UUID) (*entities.Task, error) {
+	task, err := s.taskRepo.GetByID(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		title, err := trimTitle(*req.Title)
+		if err != nil {
+			return nil, err
+		}
+		updates["title"] = title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+
+	schedule := scheduleUpdate{
+		StartAt: req.StartAt, DueAt: req.DueAt,
+		ClearStartAt: req.ClearStartAt, ClearDueAt: req.ClearDueAt,
+	}
+	if err := schedule.apply(updates, task.StartAt, task.DueAt); err != nil {
+		return nil, err
+	}
+
+	if len(updates) > 0 {
+		if err := s.taskRepo.Update(taskID, userID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.taskRepo.GetByID(taskID, userID)
+}
+
+// DeleteTask удаляет задачу вместе с подзадачами. Каскад делаем явно здесь,
+// а не полагаемся на constraint в БД (старые таблицы создавались с SET NULL)
+func (s *taskService) DeleteTask(ctx context.Context, taskID, userID uuid.UUID) error {
+	return s.taskRepo.Transaction(func(repo repositories.TaskRepository) error {
+		if _, err := repo.GetByID(taskID, userID); err != nil {
+			return err
+		}
+
+		if err := repo.DeleteSubTasksByTaskID(taskID); err != nil {
+			return err
+		}
+
+		if err := repo.DeleteTagLinks(taskID); err != nil {
+			return err
+		}
+
+		return repo.Delete(taskID, userID)
+	})
+}
+
+// TransitionTask переводит задачу в новый статус по taskStatusTransitions.
+// При переходе в done проставляется completed_at и, если попросили, закрываются подзадачи
+func (s *taskService) TransitionTask(ctx context.Context, req *entities.TaskTransitionReq, taskID, userID uuid.UUID) (*entities.Task, error) {
+	if !req.Status.IsValid() {
+		return nil, entities.ErrInvalidTaskStatus
+	}
+
+	err := s.taskRepo.Transaction(func(repo repositories.TaskRepository) error {
+		task, err := repo.GetByID(taskID, userID)
+		if err != nil {
+			return err
+		}
+
+		if !task.Status.CanTransitionTo(req.Status) {
+			return entities.ErrInvalidStatusTransition
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":       req.Status,
+			"completed_at": nil,
+		}
+		if req.Status == entities.TaskStatusDone {
+			updates["completed_at"] = now
+		}
+
+		if err := repo.UpdateStatus(taskID, userID, task.Status, updates); err != nil {
+			return err
+		}
+
+		if req.Status == entities.TaskStatusDone && req.CompleteSubTasks {
+			return repo.CompleteSubTasks(taskID, now)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.taskRepo.GetByID(taskID, userID)
+}
+
+func (s *taskService) CreateSubTask(ctx context.Context, req *entities.SubTaskCreateReq, taskID, userID uuid.UUID) (*entities.SubTask, error) {
+	if _, err := s.taskRepo.GetByID(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	title, err := trimTitle(req.Title)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchedule(req.StartAt, req.DueAt); err != nil {
+		return nil, err
+	}
+
+	subTask := &entities.SubTask{
+		TaskID:      taskID,
+		Title:       title,
+		Description: req.Description,
+		StartAt:     req.StartAt,
+		DueAt:       req.DueAt,
+	}
+	if req.Priority != nil {
+		subTask.Priority = *req.Priority
+	}
+
+	if err := s.taskRepo.CreateSubTask(subTask); err != nil {
+		return nil, err
+	}
+
+	return subTask, nil
+}
+
+func (s *taskService) GetSubTask(ctx context.Context, subTaskID, taskID, userID uuid.UUID) (*entities.SubTask, error) {
+	if _, err := s.taskRepo.GetByID(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.taskRepo.GetSubTask(subTaskID, taskID)
+}
+
+func (s *taskService) ListSubTasks(ctx context.Context, taskID, userID uuid.UUID) ([]entities.SubTask, error) {
+	if _, err := s.taskRepo.GetByID(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.taskRepo.ListSubTasks(taskID)
+}
+
+func (s *taskService) UpdateSubTask(ctx context.Context, req *entities.SubTaskUpdateReq, subTaskID, taskID, userID uuid.UUID) (*entities.SubTask, error) {
+	if _, err := s.taskRepo.GetByID(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	subTask, err := s.taskRepo.GetSubTask(subTaskID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		title, err := trimTitle(*req.Title)
+		if err != nil {
+			return nil, err
+		}
+		updates["title"] = title
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Completed != nil {
+		updates["completed"] = *req.Completed
+		updates["completed_at"] = nil
+		if *req.Completed {
+			updates["completed_at"] = time.Now()
+		}
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+
+	schedule := scheduleUpdate{
+		StartAt: req.StartAt, DueAt: req.DueAt,
+		ClearStartAt: req.ClearStartAt, ClearDueAt: req.ClearDueAt,
+	}
+	if err := schedule.apply(updates, subTask.StartAt, subTask.DueAt); err != nil {
+		return nil, err
+	}
+
+	if len(updates) > 0 {
+		if err := s.taskRepo.UpdateSubTask(subTaskID, taskID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.taskRepo.GetSubTask(subTaskID, taskID)
+}
+
+func (s *taskService) DeleteSubTask(ctx context.Context, subTaskID, taskID, userID uuid.UUID) error {
+	if _, err := s.taskRepo.GetByID(taskID, userID); err != nil {
+		return err
+	}
+
+	return s.taskRepo.DeleteSubTask(subTaskID, taskID)
+}
+
+// scheduleUpdate - изменения start_at/due_at из запроса на обновление
+type scheduleUpdate struct {
+	StartAt      *time.Time
+	DueAt        *time.Time
+	ClearStartAt bool
+	ClearDueAt   bool
+}
+
+// apply кладет изменения в updates и проверяет итоговые даты вместе с текущими
+func (u scheduleUpdate) apply(updates map[string]interface{}, currentStart, currentDue *time.Time) error {
+	startAt, dueAt := currentStart, currentDue
+
+	if u.ClearStartAt {
+		startAt = nil
+		updates["start_at"] = nil
+	} else if u.StartAt != nil {
+		startAt = u.StartAt
+		updates["start_at"] = *u.StartAt
+	}
+
+	if u.ClearDueAt {
+		dueAt = nil
+		updates["due_at"] = nil
+	} else if u.DueAt != nil {
+		dueAt = u.DueAt
+		updates["due_at"] = *u.DueAt
+	}
+
+	return validateSchedule(startAt, dueAt)
+}
+
+func validateSchedule(startAt, dueAt *time.Time) error {
+	if startAt != nil && dueAt != nil && startAt.After(*dueAt) {
+		return entities.ErrInvalidTaskDates
+	}
+	return nil
+}
+
+// trimTitle убирает пробелы по краям. Валидатор проверяет длину до обрезки,
+// поэтому заголовок из одних пробелов отсекаем здесь
+func trimTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", entities.ErrEmptyTitle
+	}
+	return title, nil
+}
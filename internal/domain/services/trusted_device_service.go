@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/cache"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type trustedDeviceService struct {
+	redisClient cache.RedisClient
+	secret      []byte
+	ttl         time.Duration
+}
+
+type TrustedDeviceService interface {
+	// Trust запоминает устройство и возвращает значение для cookie
+	Trust(ctx context.Context, userID uuid.UUID, userAgent, ip string) (string, error)
+	IsTrusted(ctx context.Context, userID uuid.UUID, token, userAgent string) (bool, error)
+	List(ctx context.Context, userID uuid.UUID, currentToken string) ([]entities.TrustedDeviceRes, error)
+	Revoke(ctx context.Context, userID uuid.UUID, deviceID string) error
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+}
+
+func NewTrustedDeviceService(redisClient cache.RedisClient, secret string, ttl time.Duration) TrustedDeviceService {
+	return &trustedDeviceService{
+		redisClient: redisClient,
+		secret:      []byte(secret),
+		ttl:         ttl,
+	}
+}
+
+func (s *trustedDeviceService) Trust(ctx context.Context, userID uuid.UUID, userAgent, ip string) (string, error) {
+	now := time.Now()
+	device := &entities.TrustedDevice{
+		DeviceID:   uuid.New().String(),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(s.ttl).Unix(),
+	}
+
+	if err := s.redisClient.SetStruct(ctx, trustedDeviceKey(userID, device.DeviceID), device, s.ttl); err != nil {
+		return "", err
+	}
+
+	return device.DeviceID + "." + s.sign(userID, device.DeviceID, userAgent), nil
+}
+
+// IsTrusted - подпись cookie сходится для этого пользователя и браузера, и запись
+// в Redis еще не отозвана. Чужая cookie или cookie из другого браузера не подойдут
+func (s *trustedDeviceService) IsTrusted(ctx context.Context, userID uuid.UUID, token, userAgent string) (bool, error) {
+	deviceID, ok := s.verify(userID, token, userAgent)
+	if !ok {
+		return false, nil
+	}
+
+	key := trustedDeviceKey(userID, deviceID)
+	var device entities.TrustedDevice
+	if err := s.redisClient.GetStruct(ctx, key, &device); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if device.UserAgent != userAgent || time.Now().Unix() > device.ExpiresAt {
+		return false, nil
+	}
+
+	device.LastUsedAt = time.Now().Unix()
+	ttl := time.Until(time.Unix(device.ExpiresAt, 0))
+	if err := s.redisClient.SetStruct(ctx, key, &device, ttl); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *trustedDeviceService) List(ctx context.Context, userID uuid.UUID, currentToken string) ([]entities.TrustedDeviceRes, error) {
+	var devices []entities.TrustedDevice
+	if err := s.redisClient.GetAllByKey(ctx, fmt.Sprintf("trusted_device:%s:*", userID), &devices); err != nil {
+		return nil, err
+	}
+
+	currentID, _, _ := strings.Cut(currentToken, ".")
+
+	res := make([]entities.TrustedDeviceRes, 0, len(devices))
+	for _, device := range devices {
+		res = append(res, entities.TrustedDeviceRes{
+			DeviceID:   device.DeviceID,
+			UserAgent:  device.UserAgent,
+			IP:         device.IP,
+			CreatedAt:  device.CreatedAt,
+			LastUsedAt: device.LastUsedAt,
+			ExpiresAt:  device.ExpiresAt,
+			Current:    currentID != "" && device.DeviceID == currentID,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastUsedAt > res[j].LastUsedAt
+	})
+
+	return res, nil
+}
+
+func (s *trustedDeviceService) Revoke(ctx context.Context, userID uuid.UUID, deviceID string) error {
+	key := trustedDeviceKey(userID, deviceID)
+	exists, err := s.redisClient.Exists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return entities.ErrTrustedDeviceNotFound
+	}
+
+	return s.redisClient.Delete(ctx, key)
+}
+
+func (s *trustedDeviceService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	var devices []entities.TrustedDevice
+	if err := s.redisClient.GetAllByKey(ctx, fmt.Sprintf("trusted_device:%s:*", userID), &devices); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if err := s.redisClient.Delete(ctx, trustedDeviceKey(userID, device.DeviceID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *trustedDeviceService) sign(userID uuid.UUID, deviceID, userAgent string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s:%s:%s", userID, deviceID, userAgent)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *trustedDeviceService) verify(userID uuid.UUID, token, userAgent string) (string, bool) {
+	deviceID, signature, ok := strings.Cut(token, ".")
+	if !ok || deviceID == "" {
+		return "", false
+	}
+
+	expected := s.sign(userID, deviceID, userAgent)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", false
+	}
+
+	return deviceID, true
+}
+
+func trustedDeviceKey(userID uuid.UUID, deviceID string) string {
+	return fmt.Sprintf("trusted_device:%s:%s", userID, deviceID)
+}
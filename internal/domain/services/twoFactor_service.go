@@ -1,84 +1,172 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"math/big"
-	"net/http"
-	"rest-api-notes/internal/config"
 	"rest-api-notes/internal/domain/entities"
+	"rest-api-notes/internal/infrastructure/auth"
+	"rest-api-notes/internal/infrastructure/notification"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type twoFactorService struct {
 	sS         SessionService
-	cfg        *config.Config
+	totp       auth.TOTPService
+	recovery   RecoveryCodeService
+	senders    notification.CodeSenders
 	codeLength int
 	codeExpiry time.Duration
 }
 
 type TwoFactorService interface {
-	MakeRequestToGateway(ctx context.Context, phoneNumber, code string) error
 	Generate2FACode() (string, error)
+	IssueCode(ctx context.Context, user *entities.User, context entities.TwoFASessionContext, token ...string) error
+	VerifyUserCode(ctx context.Context, user *entities.User, code string, context entities.TwoFASessionContext) (*entities.TwoFASessionData, error)
+	StartTOTPEnrollment(ctx context.Context, user *entities.User) (*entities.TOTPEnrollRes, error)
+	ValidateTOTPCode(ctx context.Context, userID uuid.UUID, secret, code string) error
+	// SealTOTPSecret - секрет в том виде, в котором он хранится в БД
+	SealTOTPSecret(secret string) (string, error)
+	OpenTOTPSecret(stored string) (string, error)
 }
 
-func NewTwoFactorService(sS SessionService, cfg *config.Config) TwoFactorService {
+func NewTwoFactorService(sS SessionService, totp auth.TOTPService,
+	recovery RecoveryCodeService, senders notification.CodeSenders) TwoFactorService {
 	return &twoFactorService{
 		sS:         sS,
-		cfg:        cfg,
+		totp:       totp,
+		recovery:   recovery,
+		senders:    senders,
 		codeLength: 4,
 		codeExpiry: 5 * time.Minute}
 }
 
-func (s *twoFactorService) MakeRequestToGateway(ctx context.Context, phoneNumber, code string) error {
-	url := "https://gatewayapi.telegram.org/sendVerificationMessage"
-
-	authorizationHeader := fmt.Sprintf("Bearer %s", s.cfg.GATEWAY_API_TOKEN)
+// IssueCode начинает проверку второго фактора. Для Telegram/почты генерируем и отправляем код,
+// для TOTP код придумывает приложение, а в Redis остается только запись о начатой проверке
+func (s *twoFactorService) IssueCode(ctx context.Context, user *entities.User, context entities.TwoFASessionContext, token ...string) error {
+	// Пока проверка заблокирована, коды не рассылаем: принять их все равно нельзя
+	if err := s.sS.Check2FALock(ctx, user.ID); err != nil {
+		return err
+	}
 
-	sendCodeBody := entities.SendCodeRequest{
-		PhoneNumber: phoneNumber,
-		Code:        code,
+	if user.UsesTOTP() {
+		return s.sS.Save2FACode(ctx, user.ID, "", context, token...)
 	}
 
-	jsonData, err := json.Marshal(sendCodeBody)
+	sender, err := s.senders.For(user.TwoFactorMethod)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	code, err := s.Generate2FACode()
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", authorizationHeader)
+	if err := s.sS.Save2FACode(ctx, user.ID, code, context, token...); err != nil {
+		return err
+	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	to := notification.Recipient{PhoneNumber: user.PhoneNumber, Email: user.Email}
+	if err := sender.SendCode(ctx, to, code); err != nil {
+		if err := s.sS.Delete2FACode(ctx, user.ID, context); err != nil {
+			return err
+		}
+		return err
 	}
 
-	resp, err := client.Do(req)
+	return nil
+}
+
+// VerifyUserCode проверяет код тем способом, который выбран у пользователя.
+// Вместо кода можно передать recovery код, если доступа к телефону/приложению нет
+func (s *twoFactorService) VerifyUserCode(ctx context.Context, user *entities.User, code string, context entities.TwoFASessionContext) (*entities.TwoFASessionData, error) {
+	if err := s.sS.Check2FALock(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	recoveryCode := IsRecoveryCode(code)
+	if !user.UsesTOTP() && !recoveryCode {
+		return s.sS.Verify2FACode(ctx, user.ID, code, context)
+	}
+
+	data, err := s.sS.Get2FAData(ctx, user.ID, context)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if recoveryCode {
+		err = s.recovery.Use(ctx, user.ID, code)
+	} else {
+		var secret string
+		secret, err = s.OpenTOTPSecret(user.TOTPSecret)
+		if err == nil {
+			err = s.ValidateTOTPCode(ctx, user.ID, secret, code)
+		}
+	}
+	if errors.Is(err, entities.Err2FACodeInvalid) {
+		return nil, s.sS.Register2FAFailure(ctx, user.ID, context)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var telegramResp entities.RequestStatus
-	if err := json.Unmarshal(body, &telegramResp); err != nil {
-		return err
+	if err := s.sS.Delete2FACode(ctx, user.ID, context); err != nil {
+		log.Printf("Failed to delete 2FA code: %v", err)
 	}
 
-	if telegramResp.DeliveryStatus.Status == "revoked" {
-		return entities.Err2FACodeRevoked
+	return data, nil
+}
+
+func (s *twoFactorService) StartTOTPEnrollment(ctx context.Context, user *entities.User) (*entities.TOTPEnrollRes, error) {
+	secret, err := s.totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	uri := s.totp.ProvisioningURI(secret, user.Email)
+	png, err := s.totp.QRCodePNG(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sS.SavePendingTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &entities.TOTPEnrollRes{
+		Secret:     secret,
+		OtpauthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+func (s *twoFactorService) SealTOTPSecret(secret string) (string, error) {
+	return s.totp.SealSecret(secret)
+}
+
+func (s *twoFactorService) OpenTOTPSecret(stored string) (string, error) {
+	return s.totp.OpenSecret(stored)
+}
+
+func (s *twoFactorService) ValidateTOTPCode(ctx context.Context, userID uuid.UUID, secret, code string) error {
+	step, ok := s.totp.Validate(secret, code, time.Now())
+	if !ok {
+		return entities.Err2FACodeInvalid
+	}
+
+	fresh, err := s.sS.MarkTOTPStepUsed(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return entities.Err2FACodeInvalid
 	}
 
 	return nil
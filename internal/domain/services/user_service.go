@@ -2,8 +2,14 @@ package services
 
 import (
 	"context"
+	"errors"
+	"log"
 	"rest-api-notes/internal/domain/entities"
 	"rest-api-notes/internal/domain/repositories"
+	"rest-api-notes/internal/infrastructure/auth"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,22 +18,60 @@ type UserService interface {
 	GetUserProfile(userId uuid.UUID) (*entities.User, error)
 	UpdateUserPhone(ctx context.Context, req *entities.UserUpdatePhoneReq, userID uuid.UUID) error
 	TwoFactorToggleRequest(ctx context.Context, userID uuid.UUID) error
-	VerifyTwoFactorToggleRequest(ctx context.Context, userID uuid.UUID, code string) error
+	// При включении 2FA возвращает новый набор recovery кодов, при выключении nil
+	VerifyTwoFactorToggleRequest(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
 	ResendTwoFactorCode(ctx context.Context, userID uuid.UUID) error
+	ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]entities.SessionRes, error)
+	RevokeSession(ctx context.Context, userID uuid.UUID, sessionID string) error
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID, exceptSessionID string) error
+	EnrollTOTP(ctx context.Context, userID uuid.UUID, req *entities.TOTPEnrollReq) (*entities.TOTPEnrollRes, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, req *entities.TOTPConfirmReq) ([]string, error)
+	SetTwoFactorMethod(ctx context.Context, userID uuid.UUID, req *entities.TwoFactorMethodReq) error
+	// SealLegacyTOTPSecrets шифрует TOTP секреты, сохраненные до появления шифрования
+	SealLegacyTOTPSecrets(ctx context.Context) error
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, req *entities.RecoveryCodesRegenerateReq) ([]string, error)
+	ListTrustedDevices(ctx context.Context, userID uuid.UUID, currentToken string) ([]entities.TrustedDeviceRes, error)
+	RevokeTrustedDevice(ctx context.Context, userID uuid.UUID, deviceID string) error
+	RevokeAllTrustedDevices(ctx context.Context, userID uuid.UUID) error
+	// RequestReauthCode отправляет код для подтверждения чувствительного действия
+	RequestReauthCode(ctx context.Context, userID uuid.UUID) error
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentSessionID string, req *entities.UserChangePasswordReq) error
+	ResendEmailVerification(ctx context.Context, userID uuid.UUID) error
+	ChangeUsername(ctx context.Context, userID uuid.UUID, req *entities.UserChangeUsernameReq) error
+	// RequestEmailChange отправляет подтверждение на новый адрес, сама почта пока не меняется
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, req *entities.EmailChangeReq) error
+	// DeleteAccount помечает аккаунт на удаление и возвращает время окончательного удаления
+	DeleteAccount(ctx context.Context, userID uuid.UUID, req *entities.UserDeleteAccountReq) (time.Time, error)
 }
 
 type userService struct {
-	userRepo         repositories.UserRepository
-	sessionService   SessionService
-	twoFactorService TwoFactorService
+	userRepo            repositories.UserRepository
+	sessionService      SessionService
+	twoFactorService    TwoFactorService
+	recoveryCodeService RecoveryCodeService
+	trustedDevices      TrustedDeviceService
+	passwordService     auth.PasswordService
+	emailVerification   EmailVerificationService
+	emailChange         EmailChangeService
+	accountDeletion     AccountDeletionService
 }
 
-func NewUserService(userRepo repositories.UserRepository,
-	sessionService SessionService, twoFactorService TwoFactorService) UserService {
+func NewUserService(userRepo repositories.UserRepository, sessionService SessionService,
+	twoFactorService TwoFactorService, recoveryCodeService RecoveryCodeService,
+	trustedDevices TrustedDeviceService, passwordService auth.PasswordService,
+	emailVerification EmailVerificationService, emailChange EmailChangeService,
+	accountDeletion AccountDeletionService) UserService {
 	return &userService{
-		userRepo:         userRepo,
-		sessionService:   sessionService,
-		twoFactorService: twoFactorService,
+		userRepo:            userRepo,
+		sessionService:      sessionService,
+		twoFactorService:    twoFactorService,
+		recoveryCodeService: recoveryCodeService,
+		trustedDevices:      trustedDevices,
+		passwordService:     passwordService,
+		emailVerification:   emailVerification,
+		emailChange:         emailChange,
+		accountDeletion:     accountDeletion,
 	}
 }
 
@@ -50,6 +94,10 @@ func (s *userService) UpdateUserPhone(ctx context.Context, req *entities.UserUpd
 		return entities.ErrCantChangePhone2FA
 	}
 
+	if err := s.emailVerification.RequireVerified(user, entities.EmailPolicyActionPhone); err != nil {
+		return err
+	}
+
 	if err = s.userRepo.UpdatePhoneNumber(req.PhoneNumber, userID); err != nil {
 		return err
 	}
@@ -63,71 +111,396 @@ func (s *userService) TwoFactorToggleRequest(ctx context.Context, userID uuid.UU
 		return err
 	}
 
-	if user.PhoneNumber == "" {
-		return entities.ErrNoPhoneNumberToEnable2FA
+	// Выключить 2FA политика не мешает, закрыто только включение
+	if !user.TwoFactorEnabled {
+		if err := s.emailVerification.RequireVerified(user, entities.EmailPolicyAction2FA); err != nil {
+			return err
+		}
+	}
+
+	return s.twoFactorService.IssueCode(ctx, user, entities.TwoFAContextToggle)
+}
+
+func (s *userService) VerifyTwoFactorToggleRequest(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.twoFactorService.VerifyUserCode(ctx, user, code, entities.TwoFAContextToggle); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.ToggleUser2FA(userID); err != nil {
+		return nil, err
+	}
+
+	// 2FA выключена - recovery коды и доверенные устройства больше ничего не значат
+	if user.TwoFactorEnabled {
+		if err := s.trustedDevices.RevokeAll(ctx, userID); err != nil {
+			return nil, err
+		}
+		return nil, s.recoveryCodeService.DeleteAll(ctx, userID)
+	}
+
+	return s.recoveryCodeService.Generate(ctx, userID)
+}
+
+func (s *userService) ResendTwoFactorCode(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.twoFactorService.IssueCode(ctx, user, entities.TwoFAContextToggle)
+}
+
+func (s *userService) ListSessions(ctx context.Context, userID uuid.UUID, currentSessionID string) ([]entities.SessionRes, error) {
+	sessions, err := s.sessionService.GetAllUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]entities.SessionRes, 0, len(*sessions))
+	for _, session := range *sessions {
+		if session.IsExpired() {
+			continue
+		}
+		res = append(res, entities.SessionRes{
+			SessionID: session.SessionID,
+			UserAgent: session.UserAgent,
+			IP:        session.IP,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+			Current:   session.SessionID == currentSessionID,
+		})
 	}
 
-	code, err := s.twoFactorService.Generate2FACode()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt > res[j].CreatedAt
+	})
+
+	return res, nil
+}
+
+func (s *userService) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	session, err := s.sessionService.GetSession(ctx, userID, sessionID)
 	if err != nil {
 		return err
 	}
+	if session == nil {
+		return entities.ErrSessionNotFound
+	}
+
+	return s.sessionService.DeleteSession(ctx, userID, sessionID)
+}
+
+func (s *userService) RevokeAllSessions(ctx context.Context, userID uuid.UUID, exceptSessionID string) error {
+	return s.sessionService.DeleteAllUserSessions(ctx, userID, exceptSessionID)
+}
+
+func (s *userService) EnrollTOTP(ctx context.Context, userID uuid.UUID, req *entities.TOTPEnrollReq) (*entities.TOTPEnrollRes, error) {
+	user, err := s.reauthIf2FAEnabled(ctx, userID, &req.ReauthReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.emailVerification.RequireVerified(user, entities.EmailPolicyAction2FA); err != nil {
+		return nil, err
+	}
+
+	return s.twoFactorService.StartTOTPEnrollment(ctx, user)
+}
+
+// ConfirmTOTP - первый код из приложения доказывает, что секрет сохранен, после этого
+// секрет переезжает в БД и 2FA включается через приложение
+func (s *userService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, req *entities.TOTPConfirmReq) ([]string, error) {
+	user, err := s.reauthIf2FAEnabled(ctx, userID, &req.ReauthReq)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.sessionService.GetPendingTOTPSecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorService.ValidateTOTPCode(ctx, userID, secret, req.Code); err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.twoFactorService.SealTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.sessionService.Save2FACode(ctx, userID, code, "toggle"); err != nil {
+	if err := s.userRepo.EnableTOTP(userID, sealed); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionService.DeletePendingTOTPSecret(ctx, userID); err != nil {
+		log.Printf("Failed to delete pending TOTP secret: %v", err)
+	}
+
+	// Если 2FA уже была включена через Telegram, старые recovery коды остаются в силе
+	if user.TwoFactorEnabled {
+		return nil, nil
+	}
+
+	return s.recoveryCodeService.Generate(ctx, userID)
+}
+
+func (s *userService) SetTwoFactorMethod(ctx context.Context, userID uuid.UUID, req *entities.TwoFactorMethodReq) error {
+	method := req.Method
+	if !method.IsValid() {
+		return entities.ErrInvalid2FAMethod
+	}
+
+	user, err := s.reauthIf2FAEnabled(ctx, userID, &req.ReauthReq)
+	if err != nil {
 		return err
 	}
 
-	if err := s.twoFactorService.MakeRequestToGateway(ctx, user.PhoneNumber, code); err != nil {
-		if err := s.sessionService.Delete2FACode(ctx, userID, "toggle"); err != nil {
+	switch method {
+	case entities.TwoFactorMethodTelegram:
+		if user.PhoneNumber == "" {
+			return entities.ErrNoPhoneNumberToEnable2FA
+		}
+	case entities.TwoFactorMethodEmail:
+		// Коды на неподтвержденный адрес могут уйти кому угодно
+		if err := s.emailVerification.RequireVerified(user, entities.EmailPolicyAction2FA); err != nil {
 			return err
 		}
+	case entities.TwoFactorMethodTOTP:
+		if user.TOTPSecret == "" {
+			return entities.ErrTOTPNotConfigured
+		}
+	}
+
+	return s.userRepo.UpdateTwoFactorMethod(userID, method)
+}
+
+func (s *userService) SealLegacyTOTPSecrets(ctx context.Context) error {
+	users, err := s.userRepo.ListTOTPSecrets()
+	if err != nil {
 		return err
 	}
 
+	for _, user := range users {
+		secret, err := s.twoFactorService.OpenTOTPSecret(user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+		// Для старого секрета OpenTOTPSecret возвращает его же
+		if secret != user.TOTPSecret {
+			continue
+		}
+
+		sealed, err := s.twoFactorService.SealTOTPSecret(secret)
+		if err != nil {
+			return err
+		}
+		if err := s.userRepo.UpdateTOTPSecret(user.ID, sealed); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (s *userService) VerifyTwoFactorToggleRequest(ctx context.Context, userID uuid.UUID, code string) error {
-	_, err := s.userRepo.GetUserById(userID)
+func (s *userService) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.recoveryCodeService.CountRemaining(ctx, userID)
+}
+
+// RegenerateRecoveryCodes - новый набор recovery кодов равносилен второму фактору,
+// поэтому одной сессии мало: нужны пароль и код
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID,
+	req *entities.RecoveryCodesRegenerateReq) ([]string, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, entities.Err2FADisabled
+	}
+
+	if _, err := s.verifyIdentity(ctx, userID, req.CurrentPassword, req.Code); err != nil {
+		return nil, err
+	}
+
+	return s.recoveryCodeService.Generate(ctx, userID)
+}
+
+func (s *userService) ListTrustedDevices(ctx context.Context, userID uuid.UUID, currentToken string) ([]entities.TrustedDeviceRes, error) {
+	return s.trustedDevices.List(ctx, userID, currentToken)
+}
+
+func (s *userService) RevokeTrustedDevice(ctx context.Context, userID uuid.UUID, deviceID string) error {
+	return s.trustedDevices.Revoke(ctx, userID, deviceID)
+}
+
+func (s *userService) RevokeAllTrustedDevices(ctx context.Context, userID uuid.UUID) error {
+	return s.trustedDevices.RevokeAll(ctx, userID)
+}
+
+func (s *userService) RequestReauthCode(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetUserById(userID)
 	if err != nil {
 		return err
 	}
 
-	if _, err := s.sessionService.Verify2FACode(ctx, userID, code, "toggle"); err != nil {
+	if !user.TwoFactorEnabled {
+		return entities.Err2FADisabled
+	}
+
+	return s.twoFactorService.IssueCode(ctx, user, entities.TwoFAContextReauth)
+}
+
+// ChangePassword - смена пароля изнутри сессии. Одной украденной сессии мало:
+// нужен текущий пароль, а при включенной 2FA еще и код
+func (s *userService) ChangePassword(ctx context.Context, userID uuid.UUID, currentSessionID string,
+	req *entities.UserChangePasswordReq) error {
+	user, err := s.verifyIdentity(ctx, userID, req.CurrentPassword, req.Code)
+	if err != nil {
 		return err
 	}
 
-	if err := s.userRepo.ToggleUser2FA(userID); err != nil {
+	if err := validatePasswordPolicy(s.passwordService, req.NewPassword); err != nil {
 		return err
 	}
 
-	return nil
+	password, err := s.passwordService.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, password); err != nil {
+		return err
+	}
+
+	log.Printf("SECURITY: password changed user_id=%s", user.ID)
+
+	if !req.SignOutOtherSessions {
+		return nil
+	}
+
+	return s.sessionService.DeleteAllUserSessions(ctx, user.ID, currentSessionID)
 }
 
-func (s *userService) ResendTwoFactorCode(ctx context.Context, userID uuid.UUID) error {
+// verifyIdentity - повторная проверка пароля и, если 2FA включена, кода из контекста reauth.
+// Пароль проверяем первым, чтобы неверный пароль не сжигал одноразовый код
+func (s *userService) verifyIdentity(ctx context.Context, userID uuid.UUID, password, code string) (*entities.User, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.passwordService.ComparePasswords(user.Password, password); err != nil {
+		return nil, entities.ErrCurrentPasswordInvalid
+	}
+
+	if !user.TwoFactorEnabled {
+		return user, nil
+	}
+
+	if code == "" {
+		return nil, entities.Err2FACodeRequired
+	}
+
+	if _, err := s.twoFactorService.VerifyUserCode(ctx, user, code, entities.TwoFAContextReauth); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// reauthIf2FAEnabled - настройки второго фактора при включенной 2FA меняем только
+// после verifyIdentity, иначе достаточно сессии (2FA еще нечего защищать)
+func (s *userService) reauthIf2FAEnabled(ctx context.Context, userID uuid.UUID, req *entities.ReauthReq) (*entities.User, error) {
+	user, err := s.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TwoFactorEnabled {
+		return user, nil
+	}
+
+	return s.verifyIdentity(ctx, userID, req.CurrentPassword, req.ReauthCode)
+}
+
+func (s *userService) ResendEmailVerification(ctx context.Context, userID uuid.UUID) error {
 	user, err := s.userRepo.GetUserById(userID)
 	if err != nil {
 		return err
 	}
 
-	if user.PhoneNumber == "" {
-		return entities.ErrNoPhoneNumberToEnable2FA
+	if user.IsEmailVerified() {
+		return entities.ErrEmailAlreadyVerified
 	}
 
-	code, err := s.twoFactorService.Generate2FACode()
+	return s.emailVerification.SendVerification(ctx, user)
+}
+
+func (s *userService) ChangeUsername(ctx context.Context, userID uuid.UUID, req *entities.UserChangeUsernameReq) error {
+	user, err := s.userRepo.GetUserById(userID)
 	if err != nil {
 		return err
 	}
 
-	if err := s.sessionService.Save2FACode(ctx, userID, code, "toggle"); err != nil {
+	username := strings.TrimSpace(req.Username)
+	if username == user.Username {
+		return nil
+	}
+
+	if _, err := s.userRepo.GetUserByUsername(username); err == nil {
+		return entities.ErrUsernameAlreadyTaken
+	} else if !errors.Is(err, entities.ErrUserNotFound) {
 		return err
 	}
 
-	if err := s.twoFactorService.MakeRequestToGateway(ctx, user.PhoneNumber, code); err != nil {
-		if err := s.sessionService.Delete2FACode(ctx, userID, "toggle"); err != nil {
-			return err
-		}
+	return s.userRepo.UpdateUsername(userID, username)
+}
+
+// RequestEmailChange - почта это канал восстановления пароля, поэтому без пароля
+// (и 2FA, если включена) сменить ее нельзя даже из живой сессии
+func (s *userService) RequestEmailChange(ctx context.Context, userID uuid.UUID, req *entities.EmailChangeReq) error {
+	user, err := s.verifyIdentity(ctx, userID, req.CurrentPassword, req.Code)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	newEmail := strings.ToLower(strings.TrimSpace(req.NewEmail))
+	if _, err := s.userRepo.GetUserByEmail(newEmail); err == nil {
+		return entities.ErrEmailAlreadyTaken
+	} else if !errors.Is(err, entities.ErrUserNotFound) {
+		return err
+	}
+
+	return s.emailChange.Start(ctx, user, newEmail)
+}
+
+// DeleteAccount - после подтверждения паролем и 2FA аккаунт помечается на удаление,
+// все сессии и доверенные устройства отзываются сразу
+func (s *userService) DeleteAccount(ctx context.Context, userID uuid.UUID, req *entities.UserDeleteAccountReq) (time.Time, error) {
+	user, err := s.verifyIdentity(ctx, userID, req.CurrentPassword, req.Code)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	deleteAt, err := s.accountDeletion.Schedule(ctx, user.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Семейства отзываем сразу: к моменту удаления сессий уже не будет, и найти их будет не по чему
+	if err := s.sessionService.RevokeUserTokenFamilies(ctx, user.ID); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.trustedDevices.RevokeAll(ctx, user.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	return deleteAt, nil
 }
@@ -20,10 +20,12 @@ var (
 	CookieToken2Fa            = "2fa_token"
 	CookieTokenRefresh        = "refreshToken"
 	CookieTokenSessionID      = "session_id"
+	CookieTrustedDevice       = "trusted_device"
 )
 
 type jwtService struct {
-	cfg config.JWTConfig
+	cfg  config.JWTConfig
+	keys *keySet
 }
 
 // JWTType represents the type of JWT token.
@@ -40,29 +42,33 @@ type JWTService interface {
 	ValidateToken(tokenString string, jwtType JWTType) (*entities.JWTClaims, error)
 	Generate2FAToken(userID uuid.UUID, userAgent, userIp string) (string, error)
 	Validate2FAToken(tokenString string) (*entities.TwoFactorJWTClaims, error)
+	JWKS() JWKS
 }
 
-func NewJWTService(config config.JWTConfig) JWTService {
-	return &jwtService{cfg: config}
+func NewJWTService(config config.JWTConfig) (JWTService, error) {
+	keys, err := loadKeySet(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtService{cfg: config, keys: keys}, nil
 }
 
 func (s *jwtService) GenerateToken(userID uuid.UUID, sessionID string, jwtType JWTType) (string, error) {
-	var expiresAt time.Time
-	if jwtType == "access" {
-		expiresAt = time.Now().Add(time.Duration(s.cfg.JWT_ACCESS_EXPIRATION) * time.Hour)
-	} else if jwtType == "refresh" {
-		expiresAt = time.Now().Add(time.Duration(s.cfg.JWT_REFRESH_EXPIRATION) * time.Hour)
+	var lifetime time.Duration
+	if jwtType == JWTTypeAccess {
+		lifetime = time.Duration(s.cfg.JWT_ACCESS_EXPIRATION) * time.Hour
+	} else if jwtType == JWTTypeRefresh {
+		lifetime = time.Duration(s.cfg.JWT_REFRESH_EXPIRATION) * time.Hour
+	} else {
+		return "", ErrSigningToken
 	}
 
-	claims := jwt.MapClaims{
-		"user_id":    userID,
-		"session_id": sessionID,
-		"exp":        expiresAt.Unix(),
-		"type":       jwtType,
-	}
+	claims := s.registeredClaims(jwtType, lifetime)
+	claims["user_id"] = userID
+	claims["session_id"] = sessionID
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(s.cfg.JWTSecret))
+	signedToken, err := s.keys.sign(claims)
 	if err != nil {
 		return "", ErrSigningToken
 	}
@@ -72,60 +78,47 @@ func (s *jwtService) GenerateToken(userID uuid.UUID, sessionID string, jwtType J
 
 func (s *jwtService) ValidateToken(tokenString string, jwtType JWTType) (*entities.JWTClaims, error) {
 	var currentTokenError error
-	if jwtType == "refresh" {
+	if jwtType == JWTTypeRefresh {
 		currentTokenError = ErrInvalidRefreshToken
-	} else if jwtType == "access" {
+	} else if jwtType == JWTTypeAccess {
 		currentTokenError = ErrInvalidAccessToken
+	} else {
+		return nil, ErrInvalidAccessToken
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.cfg.JWTSecret), nil
-	})
+	claims, err := s.parse(tokenString, jwtType)
 	if err != nil {
 		return nil, currentTokenError
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, _ := uuid.Parse(claims["user_id"].(string))
-		sessionID, _ := claims["session_id"].(string)
-		exp, _ := claims["exp"].(float64)
-		tokenType, _ := claims["type"].(string)
-
-		if jwtType == "refresh" {
-			if tokenType != "refresh" {
-				return nil, currentTokenError
-			}
-
-			if time.Now().After(time.Unix(int64(exp), 0)) {
-				return nil, currentTokenError
-			}
-		}
-
-		return &entities.JWTClaims{
-			UserID:    userID,
-			SessionID: sessionID,
-			ExpiresAt: time.Unix(int64(exp), 0),
-			TokenType: tokenType,
-		}, nil
+	userID, err := uuid.Parse(stringClaim(claims, "user_id"))
+	if err != nil {
+		return nil, currentTokenError
+	}
+	sessionID := stringClaim(claims, "session_id")
+	if sessionID == "" {
+		return nil, currentTokenError
 	}
-	return nil, currentTokenError
+	exp, _ := claims.GetExpirationTime()
+	iat, _ := claims.GetIssuedAt()
+
+	return &entities.JWTClaims{
+		ID:        stringClaim(claims, "jti"),
+		UserID:    userID,
+		SessionID: sessionID,
+		IssuedAt:  iat.Time,
+		ExpiresAt: exp.Time,
+		TokenType: string(jwtType),
+	}, nil
 }
 
 func (s *jwtService) Generate2FAToken(userID uuid.UUID, userAgent, userIp string) (string, error) {
-	expiresAt := time.Now().Add(time.Duration(s.cfg.JWT_2FA_EXPIRATION) * time.Minute)
-
-	claims := jwt.MapClaims{
-		"user_id":    userID,
-		"user_agent": userAgent,
-		"user_ip":    userIp,
-		"exp":        expiresAt.Unix(),
-	}
+	claims := s.registeredClaims(JWTType2FA, time.Duration(s.cfg.JWT_2FA_EXPIRATION)*time.Minute)
+	claims["user_id"] = userID
+	claims["user_agent"] = userAgent
+	claims["user_ip"] = userIp
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(s.cfg.JWTSecret))
+	signedToken, err := s.keys.sign(claims)
 	if err != nil {
 		return "", ErrSigningToken
 	}
@@ -134,32 +127,87 @@ func (s *jwtService) Generate2FAToken(userID uuid.UUID, userAgent, userIp string
 }
 
 func (s *jwtService) Validate2FAToken(tokenString string) (*entities.TwoFactorJWTClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.cfg.JWTSecret), nil
-	})
+	claims, err := s.parse(tokenString, JWTType2FA)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrInvalid2FATokenExpired
+	}
 	if err != nil {
 		return nil, ErrInvalid2FAToken
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, _ := uuid.Parse(claims["user_id"].(string))
-		userAgent, _ := claims["user_agent"].(string)
-		userIP, _ := claims["user_ip"].(string)
-		exp, _ := claims["exp"].(float64)
+	userID, err := uuid.Parse(stringClaim(claims, "user_id"))
+	if err != nil {
+		return nil, ErrInvalid2FAToken
+	}
+	exp, _ := claims.GetExpirationTime()
+
+	return &entities.TwoFactorJWTClaims{
+		ID:        stringClaim(claims, "jti"),
+		UserID:    userID,
+		UserAgent: stringClaim(claims, "user_agent"),
+		UserIP:    stringClaim(claims, "user_ip"),
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+// registeredClaims - общие для всех типов токенов claims (RFC 7519)
+func (s *jwtService) registeredClaims(jwtType JWTType, lifetime time.Duration) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":  s.cfg.JWT_ISSUER,
+		"aud":  s.cfg.JWT_AUDIENCE,
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+		"exp":  now.Add(lifetime).Unix(),
+		"jti":  uuid.NewString(),
+		"type": jwtType,
+	}
+}
 
-		if time.Now().After(time.Unix(int64(exp), 0)) {
-			return nil, ErrInvalid2FATokenExpired
-		}
+// parse проверяет подпись, iss/aud/exp/nbf/iat с допуском на рассинхрон часов
+// и то, что токен именно ожидаемого типа: 2FA токен не пройдет как access
+func (s *jwtService) parse(tokenString string, jwtType JWTType) (jwt.MapClaims, error) {
+	parser := jwt.NewParser(
+		jwt.WithIssuer(s.cfg.JWT_ISSUER),
+		jwt.WithAudience(s.cfg.JWT_AUDIENCE),
+		jwt.WithLeeway(time.Duration(s.cfg.JWT_CLOCK_SKEW)*time.Second),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+
+	claims := jwt.MapClaims{}
+	token, err := parser.ParseWithClaims(tokenString, claims, s.keys.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
 
-		return &entities.TwoFactorJWTClaims{
-			UserID:    userID,
-			UserAgent: userAgent,
-			UserIP:    userIP,
-			ExpiresAt: time.Unix(int64(exp), 0),
-		}, nil
+	// Библиотека проверяет nbf и iat, только если они есть, а нам они обязательны
+	if _, ok := claims["nbf"]; !ok {
+		return nil, jwt.ErrTokenRequiredClaimMissing
 	}
-	return nil, ErrInvalid2FAToken
+	if _, ok := claims["iat"]; !ok {
+		return nil, jwt.ErrTokenRequiredClaimMissing
+	}
+	if stringClaim(claims, "jti") == "" {
+		return nil, jwt.ErrTokenRequiredClaimMissing
+	}
+	if stringClaim(claims, "type") != string(jwtType) {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims, nil
+}
+
+func stringClaim(claims jwt.MapClaims, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
+
+// JWKS - публичные ключи для проверки наших токенов другими сервисами.
+// В режиме HS256 список пустой, секрет наружу не отдаем
+func (s *jwtService) JWKS() JWKS {
+	return s.keys.jwks()
 }
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"errors"
+	"rest-api-notes/internal/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newTestJWTService(t *testing.T) *jwtService {
+	t.Helper()
+	svc, err := NewJWTService(config.JWTConfig{
+		JWTSecret:              "test-secret",
+		JWT_ACCESS_EXPIRATION:  1,
+		JWT_REFRESH_EXPIRATION: 24,
+		JWT_2FA_EXPIRATION:     5,
+		JWT_ISSUER:             "rest-api-notes",
+		JWT_AUDIENCE:           "rest-api-notes-clients",
+		JWT_CLOCK_SKEW:         30,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTService: %v", err)
+	}
+	return svc.(*jwtService)
+}
+
+func TestJWTServiceParse(t *testing.T) {
+	s := newTestJWTService(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		mutate  func(claims jwt.MapClaims)
+		sign    func(claims jwt.MapClaims) (string, error)
+		jwtType JWTType
+		wantErr error
+	}{
+		{
+			name:    "valid access token",
+			jwtType: JWTTypeAccess,
+		},
+		{
+			name:    "wrong token type",
+			jwtType: JWTTypeRefresh,
+			wantErr: jwt.ErrTokenInvalidClaims,
+		},
+		{
+			name:    "expired",
+			mutate:  func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name:    "expired within clock skew",
+			mutate:  func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() },
+			jwtType: JWTTypeAccess,
+		},
+		{
+			name:    "not valid yet",
+			mutate:  func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name:    "issued in the future",
+			mutate:  func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenUsedBeforeIssued,
+		},
+		{
+			name:    "missing exp",
+			mutate:  func(c jwt.MapClaims) { delete(c, "exp") },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "missing nbf",
+			mutate:  func(c jwt.MapClaims) { delete(c, "nbf") },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "missing iat",
+			mutate:  func(c jwt.MapClaims) { delete(c, "iat") },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "missing jti",
+			mutate:  func(c jwt.MapClaims) { delete(c, "jti") },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:    "wrong issuer",
+			mutate:  func(c jwt.MapClaims) { c["iss"] = "someone-else" },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name:    "wrong audience",
+			mutate:  func(c jwt.MapClaims) { c["aud"] = "someone-else" },
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name: "signed with another secret",
+			sign: func(c jwt.MapClaims) (string, error) {
+				return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("other-secret"))
+			},
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "alg none",
+			sign: func(c jwt.MapClaims) (string, error) {
+				return jwt.NewWithClaims(jwt.SigningMethodNone, c).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			},
+			jwtType: JWTTypeAccess,
+			wantErr: jwt.ErrTokenUnverifiable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := s.registeredClaims(JWTTypeAccess, time.Hour)
+			claims["user_id"] = uuid.New()
+			claims["session_id"] = uuid.NewString()
+			if tt.mutate != nil {
+				tt.mutate(claims)
+			}
+
+			sign := s.keys.sign
+			if tt.sign != nil {
+				sign = func(c jwt.Claims) (string, error) { return tt.sign(c.(jwt.MapClaims)) }
+			}
+			token, err := sign(claims)
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+
+			_, err = s.parse(token, tt.jwtType)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("parse: unexpected error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parse: got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTServiceValidateTokenRoundTrip(t *testing.T) {
+	s := newTestJWTService(t)
+	userID := uuid.New()
+	sessionID := uuid.NewString()
+
+	for _, jwtType := range []JWTType{JWTTypeAccess, JWTTypeRefresh} {
+		t.Run(string(jwtType), func(t *testing.T) {
+			token, err := s.GenerateToken(userID, sessionID, jwtType)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+
+			claims, err := s.ValidateToken(token, jwtType)
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if claims.UserID != userID || claims.SessionID != sessionID || claims.TokenType != string(jwtType) {
+				t.Fatalf("ValidateToken: got %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"rest-api-notes/internal/config"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownSigningKey       = errors.New("unknown signing key")
+	ErrUnsupportedSigningAlg   = errors.New("unsupported JWT signing algorithm")
+	ErrMissingSigningKeyConfig = errors.New("JWT_PRIVATE_KEY_PATH and JWT_KEY_ID are required for asymmetric signing")
+)
+
+const (
+	SigningAlgHS256 = "HS256"
+	SigningAlgRS256 = "RS256"
+	SigningAlgEdDSA = "EdDSA"
+)
+
+type verificationKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public interface{}
+}
+
+// keySet - ключ для подписи и все ключи, которыми еще принимаем токены.
+// При ротации новый ключ становится JWT_PRIVATE_KEY_PATH, а публичный
+// старого переезжает в JWT_VERIFICATION_KEYS, пока не истекут его токены
+type keySet struct {
+	method       jwt.SigningMethod
+	kid          string
+	signingKey   interface{}
+	verification map[string]verificationKey
+}
+
+// JWK - публичный ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func loadKeySet(cfg config.JWTConfig) (*keySet, error) {
+	alg := cfg.JWT_SIGNING_ALG
+	if alg == "" {
+		alg = SigningAlgHS256
+	}
+
+	if alg == SigningAlgHS256 {
+		return &keySet{
+			method:       jwt.SigningMethodHS256,
+			kid:          cfg.JWT_KEY_ID,
+			signingKey:   []byte(cfg.JWTSecret),
+			verification: map[string]verificationKey{},
+		}, nil
+	}
+
+	if cfg.JWT_PRIVATE_KEY_PATH == "" || cfg.JWT_KEY_ID == "" {
+		return nil, ErrMissingSigningKeyConfig
+	}
+
+	data, err := os.ReadFile(cfg.JWT_PRIVATE_KEY_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	ks := &keySet{kid: cfg.JWT_KEY_ID, verification: map[string]verificationKey{}}
+	switch alg {
+	case SigningAlgRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA private key: %w", err)
+		}
+		ks.method, ks.signingKey = jwt.SigningMethodRS256, key
+		ks.verification[ks.kid] = verificationKey{kid: ks.kid, method: ks.method, public: &key.PublicKey}
+	case SigningAlgEdDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Ed25519 private key: %w", err)
+		}
+		ks.method, ks.signingKey = jwt.SigningMethodEdDSA, key
+		ks.verification[ks.kid] = verificationKey{kid: ks.kid, method: ks.method, public: key.(ed25519.PrivateKey).Public()}
+	default:
+		return nil, ErrUnsupportedSigningAlg
+	}
+
+	// Формат: "kid1=/path/old_rsa.pub,kid2=/path/old_ed25519.pub"
+	for _, entry := range strings.Split(cfg.JWT_VERIFICATION_KEYS, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid JWT_VERIFICATION_KEYS entry %q, expected kid=path", entry)
+		}
+
+		key, err := loadPublicKey(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		ks.verification[kid] = *key
+	}
+
+	return ks, nil
+}
+
+func loadPublicKey(kid, path string) (*verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT verification key %s: %w", kid, err)
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &verificationKey{kid: kid, method: jwt.SigningMethodRS256, public: key}, nil
+	}
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return &verificationKey{kid: kid, method: jwt.SigningMethodEdDSA, public: key}, nil
+	}
+
+	return nil, fmt.Errorf("JWT verification key %s is neither RSA nor Ed25519 public key", kid)
+}
+
+func (ks *keySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.method, claims)
+	if ks.kid != "" {
+		token.Header["kid"] = ks.kid
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// keyFunc выбирает ключ по kid и проверяет, что alg токена совпадает с типом ключа,
+// иначе можно было бы подсунуть HS256 токен, подписанный публичным ключом
+func (ks *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks.method == jwt.SigningMethodHS256 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ks.signingKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verification[kid]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.public, nil
+}
+
+func (ks *keySet) jwks() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.verification {
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+	return jwks
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	totpSecretSize = 20
+	totpDigits     = 6
+	totpPeriod     = 30
+	// Принимаем код из соседних интервалов, если часы телефона немного убежали
+	totpSkewSteps = 1
+	totpQRSize    = 256
+	// Префикс зашифрованного секрета. В base32 двоеточия нет, так что
+	// секреты, сохраненные до шифрования, с ним не спутать
+	totpSealedPrefix = "v1:"
+)
+
+var (
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	ErrInvalidSealedTOTPSecret = errors.New("invalid encrypted TOTP secret")
+)
+
+type totpService struct {
+	issuer string
+	aead   cipher.AEAD
+}
+
+// TOTPService - коды из приложения-аутентификатора по RFC 6238 (HMAC-SHA1, 6 цифр, 30 секунд)
+type TOTPService interface {
+	GenerateSecret() (string, error)
+	ProvisioningURI(secret, accountName string) string
+	QRCodePNG(uri string) ([]byte, error)
+	// Validate возвращает номер интервала совпавшего кода, чтобы не принять его повторно
+	Validate(secret, code string, at time.Time) (int64, bool)
+	// SealSecret шифрует секрет для хранения в БД (AES-256-GCM)
+	SealSecret(secret string) (string, error)
+	// OpenSecret расшифровывает секрет из БД. Незашифрованный (старый) секрет возвращается как есть
+	OpenSecret(stored string) (string, error)
+}
+
+// encryptionKey - произвольная строка, ключ AES получаем из нее через SHA-256
+func NewTOTPService(issuer, encryptionKey string) (TOTPService, error) {
+	key := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totpService{issuer: issuer, aead: aead}, nil
+}
+
+func (s *totpService) GenerateSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+func (s *totpService) ProvisioningURI(secret, accountName string) string {
+	label := url.PathEscape(fmt.Sprintf("%s:%s", s.issuer, accountName))
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", s.issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+func (s *totpService) QRCodePNG(uri string) ([]byte, error) {
+	return qrcode.Encode(uri, qrcode.Medium, totpQRSize)
+}
+
+func (s *totpService) Validate(secret, code string, at time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := at.Unix() / totpPeriod
+	for step := current - totpSkewSteps; step <= current+totpSkewSteps; step++ {
+		expected := hotp(key, step)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *totpService) SealSecret(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return totpSealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *totpService) OpenSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, totpSealedPrefix) {
+		return stored, nil
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, totpSealedPrefix))
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", ErrInvalidSealedTOTPSecret
+	}
+
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealedTOTPSecret
+	}
+	return string(secret), nil
+}
+
+// hotp - RFC 4226, TOTP это HOTP со счетчиком из текущего времени
+func hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
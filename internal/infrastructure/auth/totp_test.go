@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Общий секрет из приложений RFC 4226 и RFC 6238 (SHA1)
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func newTestTOTPService(t *testing.T) *totpService {
+	t.Helper()
+	s, err := NewTOTPService("rest-api-notes", "totp-encryption-key")
+	if err != nil {
+		t.Fatalf("NewTOTPService: %v", err)
+	}
+	return s.(*totpService)
+}
+
+func TestHOTPRFC4226Vectors(t *testing.T) {
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range want {
+		if got := hotp([]byte("12345678901234567890"), int64(counter)); got != code {
+			t.Errorf("hotp(counter=%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTPValidateRFC6238Vectors(t *testing.T) {
+	s := newTestTOTPService(t)
+
+	// В RFC коды 8-значные, у нас 6 цифр - это младшие разряды того же значения
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := s.Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+			if !ok {
+				t.Fatalf("Validate(%s, t=%d) = false", tt.code, tt.unix)
+			}
+			if want := tt.unix / totpPeriod; step != want {
+				t.Fatalf("Validate(%s, t=%d) step = %d, want %d", tt.code, tt.unix, step, want)
+			}
+		})
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	s := newTestTOTPService(t)
+	at := time.Unix(1234567890, 0)
+	current := at.Unix() / totpPeriod
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		ok     bool
+		step   int64
+	}{
+		{"current step", rfcSecret, hotp(key, current), true, current},
+		{"previous step within skew", rfcSecret, hotp(key, current-1), true, current - 1},
+		{"next step within skew", rfcSecret, hotp(key, current+1), true, current + 1},
+		{"outside skew", rfcSecret, hotp(key, current-2), false, 0},
+		{"lowercase secret", strings.ToLower(rfcSecret), hotp(key, current), true, current},
+		{"wrong code", rfcSecret, "000000", false, 0},
+		{"short code", rfcSecret, "00592", false, 0},
+		{"long code", rfcSecret, "0005924", false, 0},
+		{"invalid secret", "not base32!", hotp(key, current), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := s.Validate(tt.secret, tt.code, at)
+			if ok != tt.ok || step != tt.step {
+				t.Fatalf("Validate = (%d, %v), want (%d, %v)", step, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTOTPGenerateSecret(t *testing.T) {
+	s := newTestTOTPService(t)
+
+	secret, err := s.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret is not base32: %v", err)
+	}
+	if len(key) != totpSecretSize {
+		t.Fatalf("secret size = %d, want %d", len(key), totpSecretSize)
+	}
+}
+
+func TestTOTPSealSecret(t *testing.T) {
+	s := newTestTOTPService(t)
+	sealed, err := s.SealSecret(rfcSecret)
+	if err != nil {
+		t.Fatalf("SealSecret: %v", err)
+	}
+	if strings.Contains(sealed, rfcSecret) {
+		t.Fatalf("SealSecret: secret is visible in %q", sealed)
+	}
+
+	// Меняем символ в середине: у последнего часть бит не значащие
+	i := len(totpSealedPrefix) + 10
+	flipped := byte('A')
+	if sealed[i] == 'A' {
+		flipped = 'B'
+	}
+	tampered := sealed[:i] + string(flipped) + sealed[i+1:]
+
+	other, err := NewTOTPService("rest-api-notes", "another-key")
+	if err != nil {
+		t.Fatalf("NewTOTPService: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		svc     TOTPService
+		stored  string
+		want    string
+		wantErr error
+	}{
+		{"sealed", s, sealed, rfcSecret, nil},
+		{"legacy plaintext", s, rfcSecret, rfcSecret, nil},
+		{"another key", other, sealed, "", ErrInvalidSealedTOTPSecret},
+		{"tampered", s, tampered, "", ErrInvalidSealedTOTPSecret},
+		{"not base64", s, totpSealedPrefix + "!!!", "", ErrInvalidSealedTOTPSecret},
+		{"too short", s, totpSealedPrefix + "AAAA", "", ErrInvalidSealedTOTPSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.svc.OpenSecret(tt.stored)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("OpenSecret = (%q, %v), want (%q, %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
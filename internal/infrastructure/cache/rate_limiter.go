@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type rateLimiter struct {
+	client RedisClient
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// RateLimiter - счетчики запросов в Redis, общие для всех инстансов сервера
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*RateLimitResult, error)
+}
+
+func NewRateLimiter(client RedisClient) RateLimiter {
+	return &rateLimiter{client: client}
+}
+
+// Allow - фиксированное окно: номер окна входит в ключ, поэтому старые счетчики
+// просто истекают, а сброс наступает в начале следующего окна
+func (r *rateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (*RateLimitResult, error) {
+	now := time.Now()
+	windowStart := now.Truncate(window)
+	resetAt := windowStart.Add(window)
+
+	redisKey := fmt.Sprintf("rate_limit:%s:%d", key, windowStart.Unix())
+	count, err := r.client.IncrExpire(ctx, redisKey, window)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &RateLimitResult{
+		Allowed:   int(count) <= limit,
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}, nil
+}
@@ -12,6 +12,10 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrNotFound - ключа нет (или истек). Сервисам не нужно знать про go-redis,
+// чтобы отличить промах от ошибки соединения
+var ErrNotFound = redis.Nil
+
 type redisClient struct {
 	Client *redis.Client
 }
@@ -19,8 +23,15 @@ type redisClient struct {
 type RedisClient interface {
 	SetStruct(ctx context.Context, key string, value any, expiration time.Duration) error
 	GetStruct(ctx context.Context, key string, dest any) error
+	GetDelStruct(ctx context.Context, key string, dest any) error
 	Delete(ctx context.Context, key string) error
+	DeleteByPattern(ctx context.Context, pattern string) error
 	GetAllByKey(ctx context.Context, pattern string, dest any) error
+	Exists(ctx context.Context, key string) (bool, error)
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error)
+	SetNXStruct(ctx context.Context, key string, value any, expiration time.Duration) (bool, error)
+	IncrExpire(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	Decr(ctx context.Context, key string) (int64, error)
 }
 
 func NewRedisClient(cfg *config.RedisConfig) (RedisClient, error) {
@@ -57,10 +68,30 @@ func (r *redisClient) GetStruct(ctx context.Context, key string, dest any) error
 	return json.Unmarshal([]byte(data), dest)
 }
 
+// GetDelStruct читает и удаляет ключ одной командой - для одноразовых токенов,
+// чтобы два параллельных запроса не смогли использовать один и тот же
+func (r *redisClient) GetDelStruct(ctx context.Context, key string, dest any) error {
+	data, err := r.Client.GetDel(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), dest)
+}
+
 func (r *redisClient) Delete(ctx context.Context, key string) error {
 	return r.Client.Del(ctx, key).Err()
 }
 
+func (r *redisClient) DeleteByPattern(ctx context.Context, pattern string) error {
+	iter := r.Client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		if err := r.Client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
 func (r *redisClient) GetAllByKey(ctx context.Context, pattern string, dest any) error {
 	iter := r.Client.Scan(ctx, 0, pattern, 0).Iterator()
 	for iter.Next(ctx) {
@@ -84,3 +115,45 @@ func (r *redisClient) GetAllByKey(ctx context.Context, pattern string, dest any)
 	}
 	return nil
 }
+
+func (r *redisClient) Exists(ctx context.Context, key string) (bool, error) {
+	count, err := r.Client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SetNX записывает ключ, только если его еще нет. false - ключ уже существовал
+func (r *redisClient) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	return r.Client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// SetNXStruct - SetNX для структур, значение хранится в JSON как у SetStruct
+func (r *redisClient) SetNXStruct(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return r.Client.SetNX(ctx, key, data, expiration).Result()
+}
+
+// incrExpireScript - INCR и PEXPIRE одной атомарной командой. TTL ставится, только если
+// его нет: окно считается от первого инкремента, а ключ без срока жизни (оставшийся
+// после сбоя между командами) получает его на следующем инкременте
+var incrExpireScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// IncrExpire увеличивает счетчик и гарантирует, что у него есть срок жизни
+func (r *redisClient) IncrExpire(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return incrExpireScript.Run(ctx, r.Client, []string{key}, expiration.Milliseconds()).Int64()
+}
+
+func (r *redisClient) Decr(ctx context.Context, key string) (int64, error) {
+	return r.Client.Decr(ctx, key).Result()
+}
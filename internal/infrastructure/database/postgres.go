@@ -37,14 +37,55 @@ func NewPostgresDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := MigrateSearch(db); err != nil {
+		return nil, fmt.Errorf("failed to run search migrations: %w", err)
+	}
+
 	log.Println("Database connected successfully")
 	return db, nil
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// Раньше подзадачи отвязывались через OnDelete:SET NULL, такие сироты
+	// не дадут сделать task_id NOT NULL
+	if db.Migrator().HasTable(&entities.SubTask{}) {
+		if err := db.Where("task_id IS NULL").Delete(&entities.SubTask{}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Старые индексы сортировки не совпадали с порядком keyset пагинации,
+	// их заменили *_keyset индексы
+	for _, name := range []string{"idx_tasks_user_priority", "idx_tasks_user_due"} {
+		if db.Migrator().HasIndex(&entities.Task{}, name) {
+			if err := db.Migrator().DropIndex(&entities.Task{}, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Пользователи, зарегистрированные до подтверждения почты, ссылку не получали.
+	// Колонки еще нет - значит это первый запуск с ней, и все существующие считаем подтвержденными
+	backfillEmailVerified := db.Migrator().HasTable(&entities.User{}) &&
+		!db.Migrator().HasColumn(&entities.User{}, "EmailVerifiedAt")
+
+	if err := db.AutoMigrate(
 		&entities.User{},
 		&entities.Task{},
 		&entities.SubTask{},
-	)
+		&entities.Note{},
+		&entities.Tag{},
+		&entities.RecoveryCode{},
+	); err != nil {
+		return err
+	}
+
+	if backfillEmailVerified {
+		if err := db.Model(&entities.User{}).Where("email_verified_at IS NULL").
+			Update("email_verified_at", gorm.Expr("created_at")).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
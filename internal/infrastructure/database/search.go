@@ -0,0 +1,43 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SearchConfig - конфигурация text search. simple не привязан к языку,
+// заметки бывают и на русском, и на английском
+const SearchConfig = "simple"
+
+// searchSources - таблицы с generated tsvector колонкой search_vector.
+// Заголовок весит больше (A), чем описание/тело (B)
+var searchSources = []struct {
+	table string
+	body  string
+}{
+	{"tasks", "description"},
+	{"sub_tasks", "description"},
+	{"notes", "body"},
+}
+
+// MigrateSearch добавляет search_vector и GIN индексы. AutoMigrate про
+// generated колонки не знает, поэтому делаем руками и идемпотентно
+func MigrateSearch(db *gorm.DB) error {
+	for _, source := range searchSources {
+		addColumn := fmt.Sprintf(`ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('%[3]s', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('%[3]s', coalesce(%[2]s, '')), 'B')
+			) STORED`, source.table, source.body, SearchConfig)
+		if err := db.Exec(addColumn).Error; err != nil {
+			return err
+		}
+
+		createIndex := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_search ON %[1]s USING GIN (search_vector)", source.table)
+		if err := db.Exec(createIndex).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
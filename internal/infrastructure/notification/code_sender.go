@@ -0,0 +1,80 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rest-api-notes/internal/config"
+	"rest-api-notes/internal/domain/entities"
+)
+
+const (
+	// ProviderAuto - канал выбирается по способу 2FA пользователя
+	ProviderAuto     = "auto"
+	ProviderTelegram = "telegram"
+	ProviderEmail    = "email"
+	ProviderLog      = "log"
+)
+
+var (
+	ErrUnknownProvider       = errors.New("unknown notification provider")
+	ErrLogProviderProduction = errors.New("TWO_FACTOR_PROVIDER=log is not allowed in production")
+)
+
+// Recipient - куда можно доставить код, каждый провайдер берет нужное ему поле
+type Recipient struct {
+	PhoneNumber string
+	Email       string
+}
+
+type CodeSender interface {
+	SendCode(ctx context.Context, to Recipient, code string) error
+}
+
+// CodeSenders - отправитель для каждого способа 2FA, в котором код приходит извне
+type CodeSenders map[entities.TwoFactorMethod]CodeSender
+
+func (s CodeSenders) For(method entities.TwoFactorMethod) (CodeSender, error) {
+	sender, ok := s[method]
+	if !ok {
+		return nil, entities.ErrInvalid2FAMethod
+	}
+	return sender, nil
+}
+
+// NewCodeSenders собирает отправителей по TWO_FACTOR_PROVIDER.
+// auto - Telegram или почта по выбору пользователя, telegram/email/log - один провайдер для всех.
+// log пишет живые коды в лог, поэтому в production он запрещен
+func NewCodeSenders(cfg *config.Config) (CodeSenders, error) {
+	provider := cfg.TWO_FACTOR_PROVIDER
+	if provider == "" {
+		provider = ProviderAuto
+	}
+
+	if provider == ProviderAuto {
+		return CodeSenders{
+			entities.TwoFactorMethodTelegram: NewTelegramSender(cfg.GATEWAY_API_TOKEN),
+			entities.TwoFactorMethodEmail:    NewEmailSender(cfg.SMTP),
+		}, nil
+	}
+
+	var sender CodeSender
+	switch provider {
+	case ProviderTelegram:
+		sender = NewTelegramSender(cfg.GATEWAY_API_TOKEN)
+	case ProviderEmail:
+		sender = NewEmailSender(cfg.SMTP)
+	case ProviderLog:
+		if cfg.NODE_ENV == "production" {
+			return nil, ErrLogProviderProduction
+		}
+		sender = NewLogSender(cfg.TWO_FACTOR_LOG_FILE)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+
+	return CodeSenders{
+		entities.TwoFactorMethodTelegram: sender,
+		entities.TwoFactorMethodEmail:    sender,
+	}, nil
+}
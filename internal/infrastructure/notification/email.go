@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"rest-api-notes/internal/config"
+	"strings"
+	"time"
+)
+
+var ErrNoEmailRecipient = errors.New("recipient has no email address")
+
+type emailSender struct {
+	cfg config.SMTPConfig
+}
+
+// NewEmailSender - отправка кода письмом через SMTP
+func NewEmailSender(cfg config.SMTPConfig) CodeSender {
+	return &emailSender{cfg: cfg}
+}
+
+func (s *emailSender) SendCode(ctx context.Context, to Recipient, code string) error {
+	if to.Email == "" {
+		return ErrNoEmailRecipient
+	}
+
+	body := fmt.Sprintf("Your verification code: %s\r\n\r\nIf you did not try to sign in, change your password.", code)
+	return sendMail(s.cfg, to.Email, "Verification code", body)
+}
+
+type smtpMailer struct {
+	cfg config.SMTPConfig
+}
+
+func NewSMTPMailer(cfg config.SMTPConfig) Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, to, subject, body string) error {
+	if to == "" {
+		return ErrNoEmailRecipient
+	}
+	return sendMail(m.cfg, to, subject, body)
+}
+
+func sendMail(cfg config.SMTPConfig, to, subject, body string) error {
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(body)
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return smtp.SendMail(addr, auth, cfg.From, []string{to}, []byte(msg.String()))
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type logSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogSender - заглушка для локальной разработки: код пишется в файл,
+// а если путь не задан - в лог сервера
+func NewLogSender(path string) CodeSender {
+	return &logSender{path: path}
+}
+
+func (s *logSender) SendCode(ctx context.Context, to Recipient, code string) error {
+	line := fmt.Sprintf("%s 2FA code phone=%q email=%q code=%s\n",
+		time.Now().Format(time.RFC3339), to.PhoneNumber, to.Email, code)
+
+	return writeLogLine(&s.mu, s.path, line)
+}
+
+type logMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer - письма целиком пишутся в файл, а если путь не задан - в лог сервера
+func NewLogMailer(path string) Mailer {
+	return &logMailer{path: path}
+}
+
+func (m *logMailer) Send(ctx context.Context, to, subject, body string) error {
+	text := fmt.Sprintf("%s mail to=%q subject=%q\n%s\n---\n",
+		time.Now().Format(time.RFC3339), to, subject, body)
+
+	return writeLogLine(&m.mu, m.path, text)
+}
+
+func writeLogLine(mu *sync.Mutex, path, line string) error {
+	if path == "" {
+		log.Print(line)
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(line)
+	return err
+}
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rest-api-notes/internal/config"
+)
+
+const (
+	MailProviderSMTP = "smtp"
+	MailProviderLog  = "log"
+)
+
+// Mailer - транзакционные письма (сброс пароля, подтверждение почты и т.п.)
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+var ErrMailProviderNotConfigured = errors.New("MAIL_PROVIDER or SMTP_HOST must be set in production")
+
+// NewMailer выбирает доставку по MAIL_PROVIDER. Если он не задан - SMTP при
+// настроенном SMTP_HOST, иначе письма пишутся в файл/лог, но только вне production:
+// там молча терять письма со ссылками сброса пароля нельзя
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	provider := cfg.MAIL_PROVIDER
+	if provider == "" {
+		switch {
+		case cfg.SMTP.Host != "":
+			provider = MailProviderSMTP
+		case cfg.NODE_ENV == "production":
+			return nil, ErrMailProviderNotConfigured
+		default:
+			provider = MailProviderLog
+		}
+	}
+
+	switch provider {
+	case MailProviderSMTP:
+		return NewSMTPMailer(cfg.SMTP), nil
+	case MailProviderLog:
+		return NewLogMailer(cfg.MAIL_LOG_FILE), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+}
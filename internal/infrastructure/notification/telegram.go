@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"rest-api-notes/internal/domain/entities"
+	"time"
+)
+
+const telegramGatewayURL = "https://gatewayapi.telegram.org/sendVerificationMessage"
+
+type telegramSender struct {
+	token  string
+	client *http.Client
+}
+
+// NewTelegramSender - отправка кода через Telegram Gateway API
+func NewTelegramSender(token string) CodeSender {
+	return &telegramSender{
+		token: token,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *telegramSender) SendCode(ctx context.Context, to Recipient, code string) error {
+	if to.PhoneNumber == "" {
+		return entities.ErrNoPhoneNumberToEnable2FA
+	}
+
+	sendCodeBody := entities.SendCodeRequest{
+		PhoneNumber: to.PhoneNumber,
+		Code:        code,
+	}
+
+	jsonData, err := json.Marshal(sendCodeBody)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", telegramGatewayURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.token))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Шлюз отвечает ok=false и при 200, так что проверяем и статус, и тело
+	var telegramResp entities.TelegramGatewayResponse
+	if err := json.Unmarshal(body, &telegramResp); err != nil {
+		log.Printf("Telegram gateway returned unreadable response status=%d: %v", resp.StatusCode, err)
+		return entities.Err2FACodeSendFailed
+	}
+
+	if resp.StatusCode != http.StatusOK || !telegramResp.Ok {
+		log.Printf("Telegram gateway failed to send code status=%d error=%q", resp.StatusCode, telegramResp.Error)
+		return entities.Err2FACodeSendFailed
+	}
+
+	if telegramResp.Result.DeliveryStatus.Status == entities.Revoked {
+		return entities.Err2FACodeRevoked
+	}
+
+	return nil
+}